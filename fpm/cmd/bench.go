@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Inspect and maintain Frappe benches",
+	Long:  `Provides commands to check and reconcile the apps installed in a Frappe bench.`,
+	// No Run function for the base 'bench' command itself, it's a group.
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+}
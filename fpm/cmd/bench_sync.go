@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fpm/internal/bench"
+	"fpm/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchSyncBenchPath string
+	benchSyncFix       bool
+)
+
+var benchSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Reconcile sites/apps.txt with the apps installed in a bench",
+	Long: `Compares the bench's sites/apps.txt with its apps directory and reports
+symlinked apps missing from apps.txt and apps.txt entries with no app present.
+With --fix, valid symlinked apps are appended to apps.txt. Entries without an
+app are only reported, since fpm cannot know where they should come from.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		absBenchPath, err := filepath.Abs(benchSyncBenchPath)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute bench path: %w", err)
+		}
+
+		out := cmd.OutOrStdout()
+		report, err := bench.CheckSync(absBenchPath)
+		if err != nil {
+			return err
+		}
+		if report.InSync() {
+			fmt.Fprintln(out, utils.Success(out, fmt.Sprintf("Bench '%s' is in sync with %s", absBenchPath, bench.AppsTxtPath(absBenchPath))))
+			return nil
+		}
+
+		for _, app := range report.UnlistedSymlinks {
+			fmt.Fprintln(out, utils.Error(out, fmt.Sprintf("Symlinked app '%s' is missing from apps.txt", app)))
+		}
+		for _, app := range report.MissingApps {
+			fmt.Fprintln(out, utils.Error(out, fmt.Sprintf("apps.txt lists '%s' but no app exists at %s", app, filepath.Join(absBenchPath, bench.AppsDirName, app))))
+		}
+
+		if benchSyncFix && len(report.UnlistedSymlinks) > 0 {
+			if err := bench.AppendAppsTxt(absBenchPath, report.UnlistedSymlinks); err != nil {
+				return fmt.Errorf("failed to update apps.txt: %w", err)
+			}
+			fmt.Fprintf(out, "%s %d app(s) to %s\n", utils.Success(out, "Added"), len(report.UnlistedSymlinks), bench.AppsTxtPath(absBenchPath))
+		}
+		return nil
+	},
+}
+
+func init() {
+	benchCmd.AddCommand(benchSyncCmd)
+	benchSyncCmd.Flags().StringVar(&benchSyncBenchPath, "bench-path", ".", "Path to the Frappe bench")
+	benchSyncCmd.Flags().BoolVar(&benchSyncFix, "fix", false, "Append valid symlinked apps missing from apps.txt")
+}
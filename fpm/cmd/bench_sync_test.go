@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBenchSyncCommand(t *testing.T) {
+	t.Cleanup(func() { benchSyncBenchPath, benchSyncFix = ".", false })
+	benchPath := createEmptyBench(t, "frappe\nghost_app\n")
+	if err := os.Mkdir(filepath.Join(benchPath, "apps", "frappe"), 0755); err != nil {
+		t.Fatalf("Failed to create frappe dir: %v", err)
+	}
+	target := filepath.Join(t.TempDir(), "linked_app")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatalf("Failed to create linked app dir: %v", err)
+	}
+	if err := os.Symlink(target, filepath.Join(benchPath, "apps", "linked_app")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	out, err := runRootCmd(t, "bench", "sync", "--bench-path", benchPath, "--fix")
+	if err != nil {
+		t.Fatalf("bench sync failed: %v", err)
+	}
+	for _, want := range []string{
+		"Symlinked app 'linked_app' is missing from apps.txt",
+		"apps.txt lists 'ghost_app' but no app exists",
+		"Added 1 app(s) to",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, out)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(benchPath, "sites", "apps.txt"), []byte("frappe\nlinked_app\n"), 0644); err != nil {
+		t.Fatalf("Failed to write apps.txt: %v", err)
+	}
+	out, err = runRootCmd(t, "bench", "sync", "--bench-path", benchPath)
+	if err != nil {
+		t.Fatalf("bench sync failed: %v", err)
+	}
+	if !strings.Contains(out, "is in sync with") {
+		t.Errorf("Expected in-sync output, got: %s", out)
+	}
+}
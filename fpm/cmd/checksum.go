@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fpm/internal/archive"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	checksumStagingDir string
+	checksumStripSrc   bool
+	checksumUseCache   bool
+	checksumAssets     string
+	checksumModuleDir  string
+)
+
+var checksumCmd = &cobra.Command{
+	Use:   "checksum [dir]",
+	Short: "Print the content checksum a package built from a directory would have",
+	Long: `Stages a Frappe app exactly as 'fpm package' does, applying the same ignore
+rules, and prints the content checksum of the result without writing an .fpm
+file. The checksum matches the contentChecksum that 'fpm package' records in
+app_metadata.json, so it can be used to tell whether a rebuild would change
+the package. The directory defaults to the current directory.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourcePath := "."
+		if len(args) == 1 {
+			sourcePath = args[0]
+		}
+		absSourcePath, err := filepath.Abs(sourcePath)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute source path: %w", err)
+		}
+		if _, err := os.Stat(absSourcePath); os.IsNotExist(err) {
+			return fmt.Errorf("source path '%s' does not exist", absSourcePath)
+		}
+		absSourcePath, _ = resolvePackageRoot(absSourcePath)
+
+		meta, err := loadOrGenerateMetadata(absSourcePath, "")
+		if err != nil {
+			return err
+		}
+		if meta.PackageName == "" {
+			return fmt.Errorf("app package name could not be determined, cannot validate structure")
+		}
+		if err := validateFrappeAppStructure(absSourcePath, meta.PackageName); err != nil {
+			return err
+		}
+
+		excludeAssets, err := parseAssetsMode(checksumAssets)
+		if err != nil {
+			return err
+		}
+		stagingDir, err := resolveStagingDir(checksumStagingDir)
+		if err != nil {
+			return err
+		}
+		opts := archive.ArchiveOptions{
+			StagingDir:            stagingDir,
+			StripSources:          checksumStripSrc,
+			ChecksumOnly:          true,
+			ExcludeCompiledAssets: excludeAssets,
+		}
+		if checksumModuleDir != "" {
+			moduleDir, err := moduleDirName(absSourcePath, meta.PackageName)
+			if err != nil {
+				return err
+			}
+			opts.ModuleDir, opts.RenameModuleDir = moduleDir, checksumModuleDir
+		}
+		if checksumUseCache {
+			opts.ChecksumCachePath, err = checksumCachePath(absSourcePath)
+			if err != nil {
+				return err
+			}
+		}
+		result, err := archive.CreateFPMArchiveWithOptions(absSourcePath, "", meta, meta.PackageVersion, opts)
+		if err != nil {
+			return fmt.Errorf("failed to compute checksum: %w", err)
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), result.ContentChecksum)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checksumCmd)
+	checksumCmd.Flags().StringVar(&checksumStagingDir, "staging-dir", "", "Directory in which to stage package contents (default is $"+stagingDirEnvVar+" or the system temp directory)")
+	checksumCmd.Flags().BoolVar(&checksumUseCache, "checksum-cache", false, "Use and update the per-file checksum cache, as 'fpm package --checksum-cache' does")
+	checksumCmd.Flags().BoolVar(&checksumStripSrc, "strip-sources", false, "Compute the checksum as for a package built with --strip-sources")
+	checksumCmd.Flags().StringVar(&checksumModuleDir, "module-dir", "", "Compute the checksum as for a package built with --module-dir")
+	checksumCmd.Flags().StringVar(&checksumAssets, "assets", "include", "Compute the checksum as for a package built with --assets include or exclude")
+}
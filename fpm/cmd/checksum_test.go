@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChecksumCommandMatchesPackage(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "sum_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "sum_app")
+	if err := os.WriteFile(filepath.Join(sourceDir, "sum_app", "api.py"), []byte("def ping():\n\treturn 'pong'\n"), 0644); err != nil {
+		t.Fatalf("Failed to create api.py: %v", err)
+	}
+
+	checksumStagingDir, checksumStripSrc = "", false
+	out, err := runRootCmd(t, "checksum", sourceDir)
+	if err != nil {
+		t.Fatalf("checksum command failed: %v", err)
+	}
+	checksum := strings.TrimSpace(out)
+	if len(checksum) != 64 {
+		t.Fatalf("Expected a SHA256 hex checksum, got '%s'", out)
+	}
+
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	meta := readPackagedMetadata(t, filepath.Join(outputDir, "sum_app-1.0.0.fpm"))
+	if meta.ContentChecksum != checksum {
+		t.Errorf("Checksum mismatch. checksum printed %s, package recorded %s", checksum, meta.ContentChecksum)
+	}
+
+	out, err = runRootCmd(t, "checksum", sourceDir, "--strip-sources")
+	checksumStripSrc = false
+	if err != nil {
+		t.Fatalf("checksum --strip-sources failed: %v", err)
+	}
+	if strings.TrimSpace(out) == checksum {
+		t.Errorf("Expected --strip-sources to change the checksum")
+	}
+}
+
+func TestChecksumCommandMatchesPackageWithoutAssets(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "noassets_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "noassets_app")
+	if err := os.MkdirAll(filepath.Join(sourceDir, "compiled_assets"), 0755); err != nil {
+		t.Fatalf("Failed to create compiled_assets: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "compiled_assets", "app.min.js"), []byte("min"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--assets", "exclude"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	meta := readPackagedMetadata(t, filepath.Join(outputDir, "noassets_app-1.0.0.fpm"))
+
+	checksumStagingDir, checksumStripSrc = "", false
+	out, err := runRootCmd(t, "checksum", sourceDir, "--assets", "exclude")
+	checksumAssets = "include"
+	if err != nil {
+		t.Fatalf("checksum --assets exclude failed: %v", err)
+	}
+	if strings.TrimSpace(out) != meta.ContentChecksum {
+		t.Errorf("Checksum mismatch. checksum --assets exclude printed %s, package recorded %s", strings.TrimSpace(out), meta.ContentChecksum)
+	}
+
+	out, err = runRootCmd(t, "checksum", sourceDir)
+	if err != nil {
+		t.Fatalf("checksum command failed: %v", err)
+	}
+	if strings.TrimSpace(out) == meta.ContentChecksum {
+		t.Errorf("Expected including compiled_assets to change the checksum")
+	}
+}
+
+func TestChecksumCommandMatchesPackageWithModuleDir(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "moved_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "moved_app")
+
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--module-dir", "app"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	meta := readPackagedMetadata(t, filepath.Join(outputDir, "moved_app-1.0.0.fpm"))
+
+	checksumStagingDir, checksumStripSrc = "", false
+	out, err := runRootCmd(t, "checksum", sourceDir, "--module-dir", "app")
+	checksumModuleDir = ""
+	if err != nil {
+		t.Fatalf("checksum --module-dir failed: %v", err)
+	}
+	if strings.TrimSpace(out) != meta.ContentChecksum {
+		t.Errorf("Checksum mismatch. checksum --module-dir printed %s, package recorded %s", strings.TrimSpace(out), meta.ContentChecksum)
+	}
+}
+
+func TestPackageCommandChecksumCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	sourceDir := filepath.Join(t.TempDir(), "cached_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "cached_app")
+
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	uncached := readPackagedMetadata(t, filepath.Join(outputDir, "cached_app-1.0.0.fpm")).ContentChecksum
+
+	for _, version := range []string{"1.0.1", "1.0.2"} {
+		if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", version, "--checksum-cache"); err != nil {
+			t.Fatalf("package command with --checksum-cache failed: %v", err)
+		}
+		fpmPath := filepath.Join(outputDir, "cached_app-"+version+".fpm")
+		if sum := readPackagedMetadata(t, fpmPath).ContentChecksum; sum != uncached {
+			t.Errorf("Checksum with cache %s differs from checksum without %s", sum, uncached)
+		}
+	}
+	cachePath, err := checksumCachePath(sourceDir)
+	if err != nil {
+		t.Fatalf("checksumCachePath failed: %v", err)
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Errorf("Expected checksum cache to be written: %v", err)
+	}
+	if !strings.HasPrefix(cachePath, os.Getenv("XDG_CACHE_HOME")) {
+		t.Errorf("Expected the checksum cache in the user cache directory, got %s", cachePath)
+	}
+}
+
+func TestPackageCommandChecksumCacheWithCleanGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	sourceDir := filepath.Join(t.TempDir(), "cached_git_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "cached_git_app")
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}, {"commit", "-q", "-m", "initial"}} {
+		cmd := exec.Command("git", append([]string{"-C", sourceDir, "-c", "user.name=fpm", "-c", "user.email=fpm@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	var checksums []string
+	for _, version := range []string{"1.0.0", "1.0.1"} {
+		if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", version, "--checksum-cache", "--require-clean-git"); err != nil {
+			t.Fatalf("package %s with --checksum-cache and --require-clean-git failed: %v", version, err)
+		}
+		fpmPath := filepath.Join(outputDir, "cached_git_app-"+version+".fpm")
+		checksums = append(checksums, readPackagedMetadata(t, fpmPath).ContentChecksum)
+	}
+	if checksums[0] != checksums[1] {
+		t.Errorf("Expected repeated cached packaging to give the same checksum, got %v", checksums)
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fpm/internal/archive"
+
+	"github.com/spf13/cobra"
+)
+
+var ignoreRulesCmd = &cobra.Command{
+	Use:   "ignore-rules [dir]",
+	Short: "Print the ignore patterns packaging would apply to an app",
+	Long: `Prints the effective, ordered list of ignore patterns 'fpm package' would
+apply to the app source in dir (default: the current directory), without
+packaging it, lowest precedence first. Each pattern is shown with its
+origin: the built-in defaults, or the .fpmignore file and line it was read
+from.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourcePath := "."
+		if len(args) == 1 {
+			sourcePath = args[0]
+		}
+		absSourcePath, err := filepath.Abs(sourcePath)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute source path: %w", err)
+		}
+		if _, err := os.Stat(absSourcePath); os.IsNotExist(err) {
+			return fmt.Errorf("source path '%s' does not exist", absSourcePath)
+		}
+		absSourcePath, _ = resolvePackageRoot(absSourcePath)
+
+		rules, err := archive.EffectiveIgnoreRules(absSourcePath)
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		for _, rule := range rules {
+			fmt.Fprintln(out, rule)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ignoreRulesCmd)
+}
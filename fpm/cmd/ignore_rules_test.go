@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRulesCommand(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "rules_app")
+	createValidFrappeApp(t, sourceDir, "rules_app")
+
+	out, err := runRootCmd(t, "ignore-rules", sourceDir)
+	if err != nil {
+		t.Fatalf("ignore-rules command failed: %v", err)
+	}
+	if !strings.Contains(out, "default ignore: *.pyc\n") {
+		t.Errorf("Expected default patterns without a .fpmignore, got '%s'", out)
+	}
+
+	if err := os.WriteFile(filepath.Join(sourceDir, ".fpmignore"), []byte("# docs\n*.md\n*.log\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .fpmignore: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "rules_app", ".fpmignore"), []byte("fixtures/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write nested .fpmignore: %v", err)
+	}
+	out, err = runRootCmd(t, "ignore-rules", sourceDir)
+	if err != nil {
+		t.Fatalf("ignore-rules command failed: %v", err)
+	}
+	expected := "default ignore: .vscode/\n.fpmignore line 2: *.md\n.fpmignore line 3: *.log\nrules_app/.fpmignore line 1: fixtures/\n"
+	if !strings.HasPrefix(out, "default ignore: .git/\n") || !strings.HasSuffix(out, expected) {
+		t.Errorf("Expected defaults followed by .fpmignore rules, got '%s'", out)
+	}
+	if strings.Contains(out, "default ignore: *.log") {
+		t.Errorf("Expected a default repeated in .fpmignore to be listed once, got '%s'", out)
+	}
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"fpm/internal/archive"
+	"fpm/internal/metadata"
+
+	"github.com/spf13/cobra"
+)
+
+var infoJSON bool
+
+// printMetadataMap writes the entries of m under title, sorted by key.
+func printMetadataMap(w io.Writer, title string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %s: %s\n", key, m[key])
+	}
+}
+
+// printAppMetadata writes meta in a readable form, skipping empty fields.
+func printAppMetadata(w io.Writer, meta *metadata.AppMetadata) {
+	fields := []struct{ label, value string }{
+		{"Package", meta.PackageName},
+		{"Version", meta.PackageVersion},
+		{"Description", meta.Description},
+		{"Author", meta.Author},
+		{"Content checksum", meta.ContentChecksum},
+		{"Frappe compatibility", strings.Join(meta.FrappeCompatibility, ", ")},
+		{"Conflicts", strings.Join(meta.Conflicts, ", ")},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			fmt.Fprintf(w, "%s: %s\n", field.label, field.value)
+		}
+	}
+	printMetadataMap(w, "Dependencies", meta.Dependencies)
+	printMetadataMap(w, "Hooks", meta.Hooks)
+	printMetadataMap(w, "Labels", meta.Labels)
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info <path.fpm>",
+	Short: "Show the metadata of an .fpm package",
+	Long: `Prints the app_metadata.json of an .fpm package without extracting it:
+its name, version, description, author, content checksum, Frappe
+compatibility, conflicts, dependencies, hooks and labels. With --json the
+metadata is printed as JSON.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		meta, err := archive.ReadPackageMetadata(args[0])
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if infoJSON {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(meta)
+		}
+		printAppMetadata(out, meta)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false, "Print the metadata as JSON")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fpm/internal/metadata"
+)
+
+func TestInfoCommand(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "info_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "info_app")
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.2.0", "--label", "build=42"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	fpmPath := filepath.Join(outputDir, "info_app-1.2.0.fpm")
+	packaged := readPackagedMetadata(t, fpmPath)
+
+	infoJSON = false
+	out, err := runRootCmd(t, "info", fpmPath)
+	if err != nil {
+		t.Fatalf("info command failed: %v", err)
+	}
+	for _, want := range []string{"Package: info_app\n", "Version: 1.2.0\n", "Content checksum: " + packaged.ContentChecksum + "\n", "Labels:\n  build: 42\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected info output to contain %q, got %q", want, out)
+		}
+	}
+
+	out, err = runRootCmd(t, "info", fpmPath, "--json")
+	infoJSON = false
+	if err != nil {
+		t.Fatalf("info --json failed: %v", err)
+	}
+	var meta metadata.AppMetadata
+	if err := json.Unmarshal([]byte(out), &meta); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+	if meta.PackageName != "info_app" || meta.ContentChecksum != packaged.ContentChecksum {
+		t.Errorf("Unexpected JSON metadata: %+v", meta)
+	}
+
+	if _, err := runRootCmd(t, "info", filepath.Join(outputDir, "missing.fpm")); err == nil {
+		t.Errorf("Expected an error for a missing package")
+	}
+}
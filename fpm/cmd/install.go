@@ -2,27 +2,172 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fpm/internal/archive"
+	"fpm/internal/bench"
+	"fpm/internal/metadata"
+	"fpm/internal/utils"
+
 	"github.com/spf13/cobra"
 )
 
+var (
+	installCheck           bool
+	installBenchPath       string
+	installIgnoreConflicts bool
+	installAppsTxt         string
+	installSite            string
+	installAppsDir         string
+	installSitesDir        string
+)
+
+// benchDir resolves dir, the --apps-dir or --sites-dir value, against
+// benchPath unless it is absolute.
+func benchDir(benchPath string, dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(benchPath, dir)
+}
+
+// extractToTemp extracts the .fpm package at fpmPath into a new temporary
+// directory. The returned cleanup function removes it; it is also removed
+// if the process is interrupted first.
+func extractToTemp(fpmPath string) (string, func(), error) {
+	extractDir, err := os.MkdirTemp("", "fpm-extract-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	unregister := utils.RegisterCleanup(func() { os.RemoveAll(extractDir) })
+	cleanup := func() {
+		os.RemoveAll(extractDir)
+		unregister()
+	}
+
+	if err := archive.ExtractFPMArchive(fpmPath, extractDir); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return extractDir, cleanup, nil
+}
+
+// checkInstall validates that the .fpm package at fpmPath could be
+// installed into the bench apps directory appsDir without changing either. The
+// package is extracted to a temporary directory, its app module structure
+// is validated, its content checksum is verified and the bench is checked for an app of the same name and,
+// unless ignoreConflicts is set, for apps the package declares a conflict
+// with, as listed in appsTxtPath. It returns the package metadata on success.
+func checkInstall(fpmPath string, appsDir string, appsTxtPath string, ignoreConflicts bool) (*metadata.AppMetadata, error) {
+	if info, err := os.Stat(appsDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("bench apps directory '%s' not found", appsDir)
+	}
+
+	extractDir, cleanup, err := extractToTemp(fpmPath)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	meta, err := metadata.LoadAppMetadata(extractDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package metadata: %w", err)
+	}
+	if meta.PackageName == "" {
+		return nil, fmt.Errorf("package metadata does not declare a package name")
+	}
+	if err := validateFrappeAppStructure(filepath.Join(extractDir, "app_source"), meta.PackageName); err != nil {
+		return nil, err
+	}
+	// Catch tampered or partially extracted packages. Packages built
+	// before content checksums were recorded have none to compare.
+	if meta.ContentChecksum != "" {
+		checksum, err := archive.ContentChecksum(extractDir)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != meta.ContentChecksum {
+			return nil, fmt.Errorf("package content checksum mismatch: metadata records %s, extracted files hash to %s", meta.ContentChecksum, checksum)
+		}
+	}
+
+	listed, err := bench.ReadAppsTxtFile(appsTxtPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", appsTxtPath, err)
+	}
+	for _, app := range listed {
+		if app == meta.PackageName {
+			return nil, fmt.Errorf("app '%s' is already listed in %s", meta.PackageName, appsTxtPath)
+		}
+		if pattern, conflicts := meta.ConflictsWith(app); conflicts && !ignoreConflicts {
+			return nil, fmt.Errorf("app '%s' conflicts with installed app '%s' (declared conflict '%s'). Use --ignore-conflicts to install it anyway", meta.PackageName, app, pattern)
+		}
+	}
+	appPath := filepath.Join(appsDir, meta.PackageName)
+	if _, err := os.Lstat(appPath); err == nil {
+		return nil, fmt.Errorf("app '%s' already exists at %s", meta.PackageName, appPath)
+	}
+	return meta, nil
+}
+
 var installCmd = &cobra.Command{
 	Use:   "install [package-name]",
 	Short: "Install a Frappe application package",
 	Long: `Installs a Frappe application from an .fpm file or a repository.
 Example: fpm install my-app-1.0.0.fpm
-         fpm install custom-app==1.0.0 --site mysite`,
+         fpm install custom-app==1.0.0 --site mysite
+         fpm install my-app-1.0.0.fpm --check --bench-path ~/frappe-bench
+
+With --check, the package is only validated against the bench: it is
+extracted to a temporary directory, its content checksum and app structure
+are verified and its apps.txt compatibility is checked. The bench is not modified and pip is not run.`,
 	Args: cobra.MinimumNArgs(0), // Can be 0 if installing from a repo with version, or 1 if a file
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if installCheck {
+			if len(args) != 1 || !strings.HasSuffix(args[0], ".fpm") {
+				return fmt.Errorf("--check requires the path of an .fpm file")
+			}
+			absBenchPath, err := filepath.Abs(installBenchPath)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute bench path: %w", err)
+			}
+			appsDir := benchDir(absBenchPath, installAppsDir)
+			appsTxtPath := bench.SiteAppsTxtPath(benchDir(absBenchPath, installSitesDir), installSite)
+			if installAppsTxt != "" {
+				if appsTxtPath, err = filepath.Abs(installAppsTxt); err != nil {
+					return fmt.Errorf("failed to get absolute apps.txt path: %w", err)
+				}
+			}
+			meta, err := checkInstall(args[0], appsDir, appsTxtPath, installIgnoreConflicts)
+			if err != nil {
+				return fmt.Errorf("install check failed for '%s': %w", args[0], err)
+			}
+			if porcelain {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s==%s\n", meta.PackageName, meta.PackageVersion)
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s '%s' version '%s' can be installed into '%s'\n", utils.Success(cmd.OutOrStdout(), "Install check passed:"), meta.PackageName, meta.PackageVersion, absBenchPath)
+			return nil
+		}
+
 		fmt.Println("fpm install called")
 		if len(args) > 0 {
 			fmt.Println("Package to install:", args[0])
 		}
 		// Logic for installation will go here
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(installCmd)
-	// Add flags for installCmd here, e.g.:
-	// installCmd.Flags().String("site", "", "Specify the site for installation")
+	installCmd.Flags().StringVar(&installSite, "site", "", "Site to install for; its sites/<site>/apps.txt is used if it has one")
+	installCmd.Flags().BoolVar(&installCheck, "check", false, "Validate the package against the bench without installing it or running pip")
+	installCmd.Flags().StringVar(&installBenchPath, "bench-path", ".", "Path to the Frappe bench")
+	installCmd.Flags().StringVar(&installAppsDir, "apps-dir", bench.AppsDirName, "Bench apps directory, relative to --bench-path unless absolute")
+	installCmd.Flags().StringVar(&installSitesDir, "sites-dir", bench.SitesDirName, "Bench sites directory, relative to --bench-path unless absolute")
+	installCmd.Flags().StringVar(&installAppsTxt, "apps-txt", "", "Path of the apps.txt to use (default is the site's or the bench's sites/apps.txt)")
+	installCmd.Flags().BoolVar(&installIgnoreConflicts, "ignore-conflicts", false, "Install even if the package declares a conflict with an installed app")
 }
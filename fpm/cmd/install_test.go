@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createEmptyBench creates a bench with an apps directory and the given apps.txt content.
+func createEmptyBench(t *testing.T, appsTxt string) string {
+	t.Helper()
+	benchPath := filepath.Join(t.TempDir(), "bench")
+	for _, dir := range []string{"apps", "sites"} {
+		if err := os.MkdirAll(filepath.Join(benchPath, dir), 0755); err != nil {
+			t.Fatalf("Failed to create bench dir %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(benchPath, "sites", "apps.txt"), []byte(appsTxt), 0644); err != nil {
+		t.Fatalf("Failed to write apps.txt: %v", err)
+	}
+	return benchPath
+}
+
+func TestInstallCheck(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "check_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "check_app")
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	fpmPath := filepath.Join(outputDir, "check_app-1.0.0.fpm")
+
+	benchPath := createEmptyBench(t, "frappe\n")
+	out, err := runRootCmd(t, "install", fpmPath, "--check", "--bench-path", benchPath)
+	if err != nil {
+		t.Fatalf("install --check failed for a valid package: %v", err)
+	}
+	if !strings.Contains(out, "Install check passed: 'check_app' version '1.0.0'") {
+		t.Errorf("Unexpected output: %s", out)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(benchPath, "apps"))
+	if err != nil {
+		t.Fatalf("Failed to read apps dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected install --check to leave apps/ untouched, found %d entries", len(entries))
+	}
+	appsTxt, err := os.ReadFile(filepath.Join(benchPath, "sites", "apps.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read apps.txt: %v", err)
+	}
+	if string(appsTxt) != "frappe\n" {
+		t.Errorf("Expected install --check to leave apps.txt untouched, got %q", appsTxt)
+	}
+
+	listedBench := createEmptyBench(t, "frappe\ncheck_app\n")
+	if _, err := runRootCmd(t, "install", fpmPath, "--check", "--bench-path", listedBench); err == nil {
+		t.Errorf("Expected install --check to fail when the app is already in apps.txt")
+	}
+}
+
+func TestInstallCheckInvalidPackage(t *testing.T) {
+	fpmPath := filepath.Join(t.TempDir(), "broken_app-1.0.0.fpm")
+	file, err := os.Create(fpmPath)
+	if err != nil {
+		t.Fatalf("Failed to create package: %v", err)
+	}
+	zipWriter := zip.NewWriter(file)
+	entries := map[string]string{
+		"app_metadata.json":                 `{"packageName": "broken_app", "packageVersion": "1.0.0"}`,
+		"app_source/broken_app/__init__.py": "",
+	}
+	for name, content := range entries {
+		w, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	file.Close()
+
+	benchPath := createEmptyBench(t, "frappe\n")
+	_, err = runRootCmd(t, "install", fpmPath, "--check", "--bench-path", benchPath)
+	if err == nil || !strings.Contains(err.Error(), "hooks.py") {
+		t.Errorf("Expected install --check to report the missing hooks.py, got %v", err)
+	}
+}
+
+func TestInstallCheckConflicts(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "new_hr")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "new_hr")
+	metadataJSON := `{"packageName": "new_hr", "conflicts": ["myorg/legacy_hr"]}`
+	if err := os.WriteFile(filepath.Join(sourceDir, "app_metadata.json"), []byte(metadataJSON), 0644); err != nil {
+		t.Fatalf("Failed to write app_metadata.json: %v", err)
+	}
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	fpmPath := filepath.Join(outputDir, "new_hr-1.0.0.fpm")
+	benchPath := createEmptyBench(t, "frappe\nlegacy_hr\n")
+
+	installIgnoreConflicts = false
+	_, err := runRootCmd(t, "install", fpmPath, "--check", "--bench-path", benchPath)
+	if err == nil || !strings.Contains(err.Error(), "conflicts with installed app 'legacy_hr'") {
+		t.Errorf("Expected install --check to refuse a conflicting app, got %v", err)
+	}
+
+	_, err = runRootCmd(t, "install", fpmPath, "--check", "--bench-path", benchPath, "--ignore-conflicts")
+	installIgnoreConflicts = false
+	if err != nil {
+		t.Errorf("Expected --ignore-conflicts to override the conflict, got %v", err)
+	}
+}
+
+func TestInstallCheckAppsTxtPath(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "site_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "site_app")
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	fpmPath := filepath.Join(outputDir, "site_app-1.0.0.fpm")
+	benchPath := createEmptyBench(t, "frappe\n")
+	defer func() { installAppsTxt, installSite = "", "" }()
+
+	customAppsTxt := filepath.Join(t.TempDir(), "apps.txt")
+	if err := os.WriteFile(customAppsTxt, []byte("frappe\nsite_app\n"), 0644); err != nil {
+		t.Fatalf("Failed to write custom apps.txt: %v", err)
+	}
+	_, err := runRootCmd(t, "install", fpmPath, "--check", "--bench-path", benchPath, "--apps-txt", customAppsTxt)
+	if err == nil || !strings.Contains(err.Error(), "already listed in "+customAppsTxt) {
+		t.Errorf("Expected --apps-txt file to be consulted, got %v", err)
+	}
+
+	installAppsTxt = ""
+	siteDir := filepath.Join(benchPath, "sites", "site1.local")
+	if err := os.MkdirAll(siteDir, 0755); err != nil {
+		t.Fatalf("Failed to create site dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(siteDir, "apps.txt"), []byte("site_app\n"), 0644); err != nil {
+		t.Fatalf("Failed to write site apps.txt: %v", err)
+	}
+	_, err = runRootCmd(t, "install", fpmPath, "--check", "--bench-path", benchPath, "--site", "site1.local")
+	if err == nil || !strings.Contains(err.Error(), filepath.Join("site1.local", "apps.txt")) {
+		t.Errorf("Expected the site's apps.txt to be consulted, got %v", err)
+	}
+
+	installSite = ""
+	if _, err := runRootCmd(t, "install", fpmPath, "--check", "--bench-path", benchPath); err != nil {
+		t.Errorf("Expected the default apps.txt to be used without overrides, got %v", err)
+	}
+	appsTxt, err := os.ReadFile(filepath.Join(benchPath, "sites", "apps.txt"))
+	if err != nil || string(appsTxt) != "frappe\n" {
+		t.Errorf("Expected the default apps.txt to be untouched, got %q (%v)", appsTxt, err)
+	}
+}
+
+func TestInstallCheckCustomBenchLayout(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "layout_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "layout_app")
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	fpmPath := filepath.Join(outputDir, "layout_app-1.0.0.fpm")
+	defer func() { installAppsDir, installSitesDir = "apps", "sites" }()
+
+	benchPath := filepath.Join(t.TempDir(), "bench")
+	for _, dir := range []string{"applications", "config"} {
+		if err := os.MkdirAll(filepath.Join(benchPath, dir), 0755); err != nil {
+			t.Fatalf("Failed to create bench dir %s: %v", dir, err)
+		}
+	}
+	appsTxtPath := filepath.Join(benchPath, "config", "apps.txt")
+	if err := os.WriteFile(appsTxtPath, []byte("frappe\nlayout_app\n"), 0644); err != nil {
+		t.Fatalf("Failed to write apps.txt: %v", err)
+	}
+	layoutArgs := []string{"install", fpmPath, "--check", "--bench-path", benchPath, "--apps-dir", "applications", "--sites-dir", "config"}
+
+	if _, err := runRootCmd(t, "install", fpmPath, "--check", "--bench-path", benchPath); err == nil || !strings.Contains(err.Error(), "apps directory") {
+		t.Errorf("Expected the default apps directory to be missing, got %v", err)
+	}
+
+	_, err := runRootCmd(t, layoutArgs...)
+	if err == nil || !strings.Contains(err.Error(), "already listed in "+appsTxtPath) {
+		t.Errorf("Expected apps.txt in --sites-dir to be consulted, got %v", err)
+	}
+
+	if err := os.WriteFile(appsTxtPath, []byte("frappe\n"), 0644); err != nil {
+		t.Fatalf("Failed to write apps.txt: %v", err)
+	}
+	if _, err := runRootCmd(t, layoutArgs...); err != nil {
+		t.Errorf("Expected install check to pass with a custom layout, got %v", err)
+	}
+
+	if err := os.Mkdir(filepath.Join(benchPath, "applications", "layout_app"), 0755); err != nil {
+		t.Fatalf("Failed to create app dir: %v", err)
+	}
+	_, err = runRootCmd(t, layoutArgs...)
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(benchPath, "applications", "layout_app")) {
+		t.Errorf("Expected an existing app in --apps-dir to be detected, got %v", err)
+	}
+}
+
+func TestInstallCheckContentChecksum(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "tamper_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "tamper_app")
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	fpmPath := filepath.Join(outputDir, "tamper_app-1.0.0.fpm")
+
+	tampered := rewritePackage(t, fpmPath, func(name string, content []byte) []byte {
+		if name == "app_source/tamper_app/hooks.py" {
+			return append(content, []byte("doc_events = {}\n")...)
+		}
+		return content
+	})
+	benchPath := createEmptyBench(t, "frappe\n")
+	_, err := runRootCmd(t, "install", tampered, "--check", "--bench-path", benchPath)
+	if err == nil || !strings.Contains(err.Error(), "content checksum mismatch") {
+		t.Errorf("Expected install --check to reject a tampered package, got %v", err)
+	}
+
+	if _, err := runRootCmd(t, "install", fpmPath, "--check", "--bench-path", benchPath); err != nil {
+		t.Errorf("Expected the untampered package to pass, got %v", err)
+	}
+}
+
+func TestInstallCheckPorcelain(t *testing.T) {
+	defer func() { porcelain = false }()
+	sourceDir := filepath.Join(t.TempDir(), "quiet_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "quiet_app")
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "2.1.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+
+	benchPath := createEmptyBench(t, "frappe\n")
+	out, err := runRootCmd(t, "install", filepath.Join(outputDir, "quiet_app-2.1.0.fpm"), "--check", "--bench-path", benchPath, "--porcelain")
+	if err != nil {
+		t.Fatalf("install --check --porcelain failed: %v", err)
+	}
+	if out != "quiet_app==2.1.0\n" {
+		t.Errorf("Expected only the app identifier, got %q", out)
+	}
+}
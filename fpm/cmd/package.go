@@ -1,29 +1,103 @@
 package cmd
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"fpm/internal/archive"
+	"fpm/internal/bench"
 	"fpm/internal/metadata"
+	"fpm/internal/utils"
+	"fpm/internal/version"
 
 	"github.com/spf13/cobra"
 )
 
+// findModuleDirByHooks scans the immediate subdirectories of sourceDir for one
+// whose hooks.py declares app_name = appName. It returns "" if none does.
+func findModuleDirByHooks(sourceDir string, appName string) (string, error) {
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		candidate := filepath.Join(sourceDir, entry.Name())
+		hooksAppName, err := metadata.GetAppNameFromHooks(filepath.Join(candidate, "hooks.py"))
+		if err != nil {
+			continue // No readable hooks.py, not the app module
+		}
+		if hooksAppName == appName {
+			return candidate, nil
+		}
+	}
+	return "", nil
+}
+
+// resolvePackageRoot returns the directory to package for sourcePath.
+// When sourcePath is itself an app module (hooks.py and modules.txt sit
+// directly in it, as when running from inside the module directory), its
+// parent is the package root. The bool reports whether that happened.
+func resolvePackageRoot(sourcePath string) (string, bool) {
+	for _, name := range []string{"hooks.py", "modules.txt"} {
+		info, err := os.Stat(filepath.Join(sourcePath, name))
+		if err != nil || info.IsDir() {
+			return sourcePath, false
+		}
+	}
+	return filepath.Dir(sourcePath), true
+}
+
+// moduleDirName returns the name of the app module directory in sourceDir:
+// appName if that directory exists, otherwise the subdirectory whose
+// hooks.py declares app_name = appName.
+func moduleDirName(sourceDir string, appName string) (string, error) {
+	if info, err := os.Stat(filepath.Join(sourceDir, appName)); err == nil && info.IsDir() {
+		return appName, nil
+	}
+	moduleDir, err := findModuleDirByHooks(sourceDir, appName)
+	if err != nil {
+		return "", err
+	}
+	if moduleDir == "" {
+		return "", fmt.Errorf("app module directory for '%s' not found in '%s'", appName, sourceDir)
+	}
+	return filepath.Base(moduleDir), nil
+}
+
 // validateFrappeAppStructure checks if the source directory has a valid Frappe app structure.
+// The app module is expected at sourceDir/appName; if it is not there, the
+// subdirectory whose hooks.py declares app_name = appName is used instead.
 func validateFrappeAppStructure(sourceDir string, appName string) error {
 	// Check 1: Existence of directory sourceDir + "/" + appName
 	innerAppPath := filepath.Join(sourceDir, appName)
 	info, err := os.Stat(innerAppPath)
-	if os.IsNotExist(err) {
-		return fmt.Errorf("Frappe app validation failed: app directory '%s' not found", innerAppPath)
-	}
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("Frappe app validation failed: error checking app directory '%s': %w", innerAppPath, err)
 	}
-	if !info.IsDir() {
-		return fmt.Errorf("Frappe app validation failed: '%s' is not a directory", innerAppPath)
+	if err != nil || !info.IsDir() {
+		// The module directory may be named differently from app_name (e.g. app_name = "hr" in frappe_hr/)
+		moduleDir, scanErr := findModuleDirByHooks(sourceDir, appName)
+		if scanErr != nil {
+			return fmt.Errorf("Frappe app validation failed: error scanning '%s' for the app module: %w", sourceDir, scanErr)
+		}
+		switch {
+		case moduleDir != "":
+			innerAppPath = moduleDir
+		case err != nil:
+			return fmt.Errorf("Frappe app validation failed: app directory '%s' not found", innerAppPath)
+		default:
+			return fmt.Errorf("Frappe app validation failed: '%s' is not a directory", innerAppPath)
+		}
 	}
 
 	// Check 2: Existence of file sourceDir + "/" + appName + "/__init__.py"
@@ -68,22 +142,206 @@ func validateFrappeAppStructure(sourceDir string, appName string) error {
 	return nil // All checks passed
 }
 
+// stagingDirEnvVar names the environment variable that selects the parent
+// directory for package staging when --staging-dir is not given.
+const stagingDirEnvVar = "FPM_STAGING_DIR"
+
 var (
-	packageSourcePath string
-	packageOutputPath string
-	packageVersion    string
-	packageOverwrite  bool
+	packageSourcePath  string
+	packageOutputPath  string
+	packageVersion     string
+	packageOverwrite   bool
+	packageStagingDir  string
+	packageKeepStaging bool
+	packageVerbose     bool
+	packageLabels      []string
+	packageRequireGit  bool
+	packageAllowNonGit bool
+	packageStripSrc    bool
+	packageStrictVer   bool
+	packageAllowNonVer bool
+	packageStdout      bool
+	packageMaxFiles    int
+	packageMaxSize     int64
+	packageModuleDir   string
+	packageSumCache    bool
+	packageBatchDir    string
+	packageJobs        int
+	packageFromBench   string
+	packageAssets      string
 )
 
+// checksumCachePath returns where the checksum cache for the app source at
+// absSourcePath is kept when --checksum-cache is given: a file named after
+// the hash of the source path in the user's cache directory. Keeping it out
+// of the source means it never shows up as an untracked file, which would
+// trip --require-clean-git.
+func checksumCachePath(absSourcePath string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the user cache directory for --checksum-cache: %w", err)
+	}
+	sum := sha256.Sum256([]byte(absSourcePath))
+	return filepath.Join(cacheDir, "fpm", "checksums", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// parseLabels converts repeated --label key=value flags into a map.
+// Later occurrences of the same key win.
+func parseLabels(labels []string) (map[string]string, error) {
+	parsed := make(map[string]string, len(labels))
+	for _, label := range labels {
+		key, value, found := strings.Cut(label, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid --label '%s': expected key=value", label)
+		}
+		parsed[key] = value
+	}
+	return parsed, nil
+}
+
+// resolveStagingDir returns the directory in which the staging directory
+// should be created. The flag value wins over FPM_STAGING_DIR; an empty
+// result means the system temp directory.
+func resolveStagingDir(flagValue string) (string, error) {
+	dir := flagValue
+	if dir == "" {
+		dir = os.Getenv(stagingDirEnvVar)
+	}
+	if dir == "" {
+		return "", nil
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute staging directory path: %w", err)
+	}
+	return absDir, nil
+}
+
+// checkCleanGitTree returns an error if sourcePath has uncommitted git
+// changes. Sources outside a git repository are an error unless allowNonGit is set.
+func checkCleanGitTree(sourcePath string, allowNonGit bool) error {
+	dirty, err := utils.GitWorkTreeDirty(sourcePath)
+	if errors.Is(err, utils.ErrNotGitRepository) {
+		if allowNonGit {
+			return nil
+		}
+		return fmt.Errorf("--require-clean-git: source path '%s' is not a git repository. Use --allow-non-git to package it anyway", sourcePath)
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("--require-clean-git: source path '%s' has uncommitted changes. Commit or stash them before packaging", sourcePath)
+	}
+	return nil
+}
+
+// loadOrGenerateMetadata loads app_metadata.json from absSourcePath, or
+// generates default metadata if it is missing or has no package name.
+// The version given on the command line always wins.
+func loadOrGenerateMetadata(absSourcePath string, packageVersion string) (*metadata.AppMetadata, error) {
+	meta, err := metadata.LoadAppMetadata(absSourcePath)
+	if err != nil || meta == nil {
+		// Unreadable metadata is treated like missing metadata and regenerated
+		meta = &metadata.AppMetadata{}
+	}
+
+	// If package name is empty (either file didn't exist or was empty), generate.
+	if meta.PackageName == "" {
+		inferredMeta, genErr := metadata.GenerateAppMetadata(absSourcePath, packageVersion)
+		if genErr != nil {
+			return nil, fmt.Errorf("failed to generate default app metadata: %w", genErr)
+		}
+		return inferredMeta, nil
+	}
+	// If loaded, still ensure the CLI version overrides
+	meta.PackageVersion = packageVersion
+	return meta, nil
+}
+
+// appNameFromBench confirms or derives the app name for the source at
+// absSourcePath from the bench at benchPath. name is confirmed if the
+// bench's sites/apps.txt lists it. Otherwise the apps.txt entry whose
+// apps/<entry> directory is absSourcePath (directly or via a symlink) is
+// returned. It is an error if neither is found.
+func appNameFromBench(benchPath string, absSourcePath string, name string) (string, error) {
+	absBenchPath, err := filepath.Abs(benchPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute bench path: %w", err)
+	}
+	listed, err := bench.ReadAppsTxt(absBenchPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", bench.AppsTxtPath(absBenchPath), err)
+	}
+	for _, app := range listed {
+		if app == name {
+			return name, nil
+		}
+	}
+
+	sourceInfo, err := os.Stat(absSourcePath)
+	if err != nil {
+		return "", err
+	}
+	for _, app := range listed {
+		info, err := os.Stat(filepath.Join(absBenchPath, bench.AppsDirName, app))
+		if err == nil && os.SameFile(info, sourceInfo) {
+			return app, nil
+		}
+	}
+	return "", fmt.Errorf("--from-bench: app '%s' is not listed in %s and the source is not installed in the bench", name, bench.AppsTxtPath(absBenchPath))
+}
+
+// parseAssetsMode reports whether an --assets value of include or exclude
+// leaves compiled_assets out of the package.
+func parseAssetsMode(mode string) (bool, error) {
+	switch mode {
+	case "include":
+		return false, nil
+	case "exclude":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid --assets '%s': expected include or exclude", mode)
+}
+
+// checkPackageVersion enforces the --strict-version and --allow-non-semver
+// policy for version, writing a warning to warnOut when it is not a
+// semantic version and neither flag is given.
+func checkPackageVersion(v string, warnOut io.Writer) error {
+	if packageAllowNonVer {
+		return nil
+	}
+	if err := version.Validate(v); err != nil {
+		if packageStrictVer {
+			return err
+		}
+		fmt.Fprintf(warnOut, "%s %v. Use --allow-non-semver to silence this warning.\n", utils.Warning(warnOut, "Warning:"), err)
+	}
+	return nil
+}
+
 var packageCmd = &cobra.Command{
 	Use:   "package",
 	Short: "Package a Frappe application into an .fpm file",
 	Long: `Packages a Frappe application from a local development directory into an .fpm file.
 It reads app metadata, collects source files, and bundles them into a versioned archive.`,
 	RunE: func(cmd *cobra.Command, args []string) error { // Using RunE for error handling
+		// When streaming the package to stdout, informational output goes to stderr
+		toStdout := packageStdout || packageOutputPath == "-"
+		if packageBatchDir != "" {
+			if toStdout {
+				return fmt.Errorf("--batch cannot be combined with --stdout or --output-path -")
+			}
+			return runBatchPackage(cmd, packageBatchDir)
+		}
+
 		if packageVersion == "" {
 			return fmt.Errorf("--version flag is required")
 		}
+		if err := checkPackageVersion(packageVersion, cmd.ErrOrStderr()); err != nil {
+			return err
+		}
 
 		absSourcePath, err := filepath.Abs(packageSourcePath)
 		if err != nil {
@@ -93,70 +351,181 @@ It reads app metadata, collects source files, and bundles them into a versioned
 		if _, err := os.Stat(absSourcePath); os.IsNotExist(err) {
 			return fmt.Errorf("source path '%s' does not exist", absSourcePath)
 		}
+		absSourcePath, insideModule := resolvePackageRoot(absSourcePath)
 
-		// Load existing metadata or generate a new one
-		meta, err := metadata.LoadAppMetadata(absSourcePath)
+		infoOut := cmd.OutOrStdout()
+		if toStdout {
+			infoOut = cmd.ErrOrStderr()
+		}
+		if porcelain {
+			infoOut = io.Discard
+		}
+
+		var archiveOut io.Writer
+		if toStdout {
+			archiveOut = cmd.OutOrStdout()
+		}
+		fpmPath, err := packageApp(absSourcePath, packageVersion, insideModule, archiveOut, infoOut)
+		if err != nil {
+			return err
+		}
+		if porcelain && !toStdout {
+			fmt.Fprintln(cmd.OutOrStdout(), fpmPath)
+		}
+		return nil
+	},
+}
+
+// packageApp packages the app at absSourcePath as version, the steps
+// shared by single and batch packaging. The archive is written to
+// archiveOut if it is not nil, otherwise to a file in --output-path whose
+// path is returned. Progress messages go to infoOut.
+func packageApp(absSourcePath string, version string, insideModule bool, archiveOut io.Writer, infoOut io.Writer) (string, error) {
+	if packageRequireGit {
+		if err := checkCleanGitTree(absSourcePath, packageAllowNonGit); err != nil {
+			return "", err
+		}
+	}
+
+	meta, err := loadOrGenerateMetadata(absSourcePath, version)
+	if err != nil {
+		return "", err
+	}
+	if packageFromBench != "" {
+		appName, err := appNameFromBench(packageFromBench, absSourcePath, meta.PackageName)
 		if err != nil {
-			// If LoadAppMetadata returns an error for reasons other than file not found,
-			// or if we decide it should error if file not found, handle here.
-			// For now, LoadAppMetadata returns empty struct if not found.
-			// Let's assume if meta.PackageName is empty after Load, we should generate.
-		}
-
-		// If package name is still empty (either file didn't exist or was empty), generate.
-		if meta.PackageName == "" {
-		    inferredMeta, genErr := metadata.GenerateAppMetadata(absSourcePath, packageVersion)
-		    if genErr != nil {
-		        return fmt.Errorf("failed to generate default app metadata: %w", genErr)
-		    }
-		    meta = inferredMeta // Use the generated one
+			return "", err
+		}
+		if appName != meta.PackageName {
+			fmt.Fprintf(infoOut, "Using app name '%s' from the bench instead of '%s'\n", appName, meta.PackageName)
+			meta.PackageName = appName
 		} else {
-            // If loaded, still ensure the CLI version overrides
-		    meta.PackageVersion = packageVersion
-        }
-        // If GenerateAppMetadata was called, it already set the version.
-        // If LoadAppMetadata was called and it was successful, PackageVersion in meta
-        // will be updated by the GenerateAppMetadata or the line above.
+			fmt.Fprintf(infoOut, "App name '%s' confirmed by the bench's apps.txt\n", appName)
+		}
+	}
 
-		// Validate Frappe app structure
-		if meta.PackageName == "" {
-			// This should ideally be caught by GenerateAppMetadata if it's responsible for determining name
-			return fmt.Errorf("app package name could not be determined, cannot validate structure")
+	labels, err := parseLabels(packageLabels)
+	if err != nil {
+		return "", err
+	}
+	if len(labels) > 0 {
+		if meta.Labels == nil {
+			meta.Labels = make(map[string]string, len(labels))
 		}
-		if err := validateFrappeAppStructure(absSourcePath, meta.PackageName); err != nil {
-			return err // The error from validateFrappeAppStructure is already descriptive
+		for key, value := range labels {
+			meta.Labels[key] = value
 		}
+	}
+
+	// Validate Frappe app structure
+	if meta.PackageName == "" {
+		// This should ideally be caught by GenerateAppMetadata if it's responsible for determining name
+		return "", fmt.Errorf("app package name could not be determined, cannot validate structure")
+	}
+	if err := validateFrappeAppStructure(absSourcePath, meta.PackageName); err != nil {
+		return "", err // The error from validateFrappeAppStructure is already descriptive
+	}
 
-		outputFileName := fmt.Sprintf("%s-%s.fpm", meta.PackageName, packageVersion)
-		absOutputPath, err := filepath.Abs(packageOutputPath)
+	var absOutputPath, finalFpmFilePath string
+	if archiveOut == nil {
+		outputFileName := fmt.Sprintf("%s-%s.fpm", meta.PackageName, version)
+		absOutputPath, err = filepath.Abs(packageOutputPath)
 		if err != nil {
-			return fmt.Errorf("failed to get absolute output path: %w", err)
+			return "", fmt.Errorf("failed to get absolute output path: %w", err)
 		}
 
-		finalFpmFilePath := filepath.Join(absOutputPath, outputFileName)
+		finalFpmFilePath = filepath.Join(absOutputPath, outputFileName)
 
 		if _, err := os.Stat(finalFpmFilePath); err == nil && !packageOverwrite {
-			return fmt.Errorf("output file '%s' already exists. Use --overwrite to replace it", finalFpmFilePath)
+			return "", fmt.Errorf("output file '%s' already exists. Use --overwrite to replace it", finalFpmFilePath)
 		}
+	}
+
+	if insideModule {
+		fmt.Fprintf(infoOut, "Source path is an app module directory, packaging its parent '%s'\n", absSourcePath)
+	}
+	fmt.Fprintf(infoOut, "Packaging '%s' version '%s' from '%s'...\n", meta.PackageName, version, absSourcePath)
 
-		fmt.Printf("Packaging '%s' version '%s' from '%s'...\n", meta.PackageName, packageVersion, absSourcePath)
+	stagingDir, err := resolveStagingDir(packageStagingDir)
+	if err != nil {
+		return "", err
+	}
 
-		err = archive.CreateFPMArchive(absSourcePath, absOutputPath, meta, packageVersion)
+	opts := archive.ArchiveOptions{
+		StagingDir:   stagingDir,
+		KeepStaging:  packageKeepStaging,
+		StripSources: packageStripSrc,
+		MaxFiles:     packageMaxFiles,
+		MaxTotalSize: packageMaxSize,
+	}
+	opts.ExcludeCompiledAssets, err = parseAssetsMode(packageAssets)
+	if err != nil {
+		return "", err
+	}
+	if packageSumCache {
+		opts.ChecksumCachePath, err = checksumCachePath(absSourcePath)
+		if err != nil {
+			return "", err
+		}
+	}
+	if packageModuleDir != "" {
+		moduleDir, err := moduleDirName(absSourcePath, meta.PackageName)
 		if err != nil {
-			return fmt.Errorf("failed to create package: %w", err)
+			return "", err
 		}
+		opts.ModuleDir, opts.RenameModuleDir = moduleDir, packageModuleDir
+	}
+	if archiveOut != nil {
+		opts.Output = archiveOut
+	}
+	result, err := archive.CreateFPMArchiveWithOptions(absSourcePath, absOutputPath, meta, version, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to create package: %w", err)
+	}
 
-		fmt.Printf("Successfully packaged: %s\n", finalFpmFilePath)
-		return nil
-	},
+	if packageVerbose {
+		for _, decision := range result.FileDecisions {
+			fmt.Fprintf(infoOut, "  %s\n", decision)
+		}
+	}
+
+	if archiveOut != nil {
+		fmt.Fprintf(infoOut, "%s '%s' version '%s' to stdout\n", utils.Success(infoOut, "Successfully packaged"), meta.PackageName, version)
+	} else {
+		fmt.Fprintf(infoOut, "%s %s\n", utils.Success(infoOut, "Successfully packaged:"), finalFpmFilePath)
+	}
+	if packageKeepStaging {
+		fmt.Fprintf(infoOut, "Staging directory kept at: %s\n", result.StagingPath)
+	}
+	return finalFpmFilePath, nil
 }
 
 func init() {
 	rootCmd.AddCommand(packageCmd)
 	packageCmd.Flags().StringVarP(&packageSourcePath, "source", "s", ".", "Path to the Frappe app source directory")
-	packageCmd.Flags().StringVarP(&packageOutputPath, "output-path", "o", ".", "Directory to save the .fpm file")
-	packageCmd.Flags().StringVarP(&packageVersion, "version", "v", "", "Package version (e.g., 1.0.0) (required)")
+	packageCmd.Flags().StringVarP(&packageOutputPath, "output-path", "o", ".", "Directory to save the .fpm file, or - to write it to stdout")
+	packageCmd.Flags().StringVarP(&packageVersion, "version", "v", "", "Package version (e.g., 1.0.0) (required, except with --batch)")
 	packageCmd.Flags().BoolVar(&packageOverwrite, "overwrite", false, "Overwrite if .fpm file already exists")
+	packageCmd.Flags().StringVar(&packageStagingDir, "staging-dir", "", "Directory in which to stage package contents (default is $"+stagingDirEnvVar+" or the system temp directory)")
+	packageCmd.Flags().BoolVar(&packageKeepStaging, "keep-staging", false, "Keep the staging directory after packaging and print its path")
+	packageCmd.Flags().BoolVar(&packageVerbose, "verbose", false, "Report, per file, whether it was included and which rule excluded it")
+	packageCmd.Flags().StringArrayVar(&packageLabels, "label", nil, "Attach a custom key=value label to the package metadata (repeatable)")
+	packageCmd.Flags().BoolVar(&packageRequireGit, "require-clean-git", false, "Refuse to package if the source git tree has uncommitted changes")
+	packageCmd.Flags().BoolVar(&packageAllowNonGit, "allow-non-git", false, "With --require-clean-git, allow packaging sources that are not in a git repository")
+	packageCmd.Flags().BoolVar(&packageStripSrc, "strip-sources", false, "Ship only compiled assets, metadata and the loaders Frappe needs (__init__.py, hooks.py, modules.txt), dropping other .py/.js sources")
+	packageCmd.Flags().BoolVar(&packageStdout, "stdout", false, "Write the .fpm archive to stdout instead of a file (same as --output-path -)")
+	packageCmd.Flags().BoolVar(&packageStrictVer, "strict-version", false, "Fail instead of warning when --version is not a semantic version (MAJOR.MINOR.PATCH)")
+	packageCmd.Flags().BoolVar(&packageAllowNonVer, "allow-non-semver", false, "Accept a --version that is not a semantic version without warning")
+	packageCmd.Flags().IntVar(&packageMaxFiles, "max-files", 0, "Fail if the package would contain more than this many files (0 means no limit)")
+	packageCmd.Flags().Int64Var(&packageMaxSize, "max-total-size", 0, "Fail if the package contents would exceed this many bytes (0 means no limit)")
+	packageCmd.Flags().BoolVar(&packageSumCache, "checksum-cache", false, "Cache per-file checksums in the user cache directory to speed up repeated packaging")
+	packageCmd.Flags().StringVar(&packageModuleDir, "module-dir", "", "Name of the app module directory inside the package (default is the source directory's name)")
+	packageCmd.Flags().StringVar(&packageBatchDir, "batch", "", "Package every Frappe app found in the subdirectories of this directory into --output-path")
+	packageCmd.Flags().IntVar(&packageJobs, "jobs", runtime.NumCPU(), "With --batch, the number of apps to package concurrently")
+	packageCmd.Flags().StringVar(&packageFromBench, "from-bench", "", "Confirm the app name against this bench's sites/apps.txt, or take it from the bench when the source is installed there")
+	packageCmd.Flags().StringVar(&packageAssets, "assets", "include", "Whether to package compiled_assets: include or exclude")
+	packageCmd.MarkFlagsMutuallyExclusive("strict-version", "allow-non-semver")
+	packageCmd.MarkFlagsMutuallyExclusive("batch", "source")
 
 	// Mark version as required if using cobra's built-in way, though manual check is also fine.
 	// packageCmd.MarkFlagRequired("version") // This causes help text to show if not provided.
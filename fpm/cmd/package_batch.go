@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"fpm/internal/metadata"
+	"fpm/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// batchResult is the outcome of packaging one app in a batch. Progress
+// messages are kept in output and warnings in warnings, so they can be
+// printed to stdout and stderr respectively once the batch is done.
+type batchResult struct {
+	dir      string
+	fpmPath  string
+	output   bytes.Buffer
+	warnings bytes.Buffer
+	err      error
+}
+
+// findBatchApps returns the subdirectories of batchDir that look like Frappe
+// app sources, i.e. contain a module directory with a hooks.py, in name order.
+func findBatchApps(batchDir string) ([]string, error) {
+	entries, err := os.ReadDir(batchDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch directory '%s': %w", batchDir, err)
+	}
+	var apps []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		appDir := filepath.Join(batchDir, entry.Name())
+		modules, err := os.ReadDir(appDir)
+		if err != nil {
+			continue
+		}
+		for _, module := range modules {
+			if !module.IsDir() {
+				continue
+			}
+			if info, err := os.Stat(filepath.Join(appDir, module.Name(), "hooks.py")); err == nil && !info.IsDir() {
+				apps = append(apps, appDir)
+				break
+			}
+		}
+	}
+	return apps, nil
+}
+
+// batchAppVersion returns the version to package the app at absSourcePath
+// as: --version if given, otherwise the version in its app_metadata.json or
+// setuptools files.
+func batchAppVersion(absSourcePath string) (string, error) {
+	if packageVersion != "" {
+		return packageVersion, nil
+	}
+	if meta, err := metadata.LoadAppMetadata(absSourcePath); err == nil && meta.PackageVersion != "" {
+		return meta.PackageVersion, nil
+	}
+	_, setupVersion, err := metadata.ReadSetupMetadata(absSourcePath)
+	if err != nil {
+		return "", err
+	}
+	if setupVersion == "" {
+		return "", fmt.Errorf("no version found in app_metadata.json or setup files; pass --version")
+	}
+	return setupVersion, nil
+}
+
+// packageBatchApp packages one app of a batch, recording its output and
+// outcome in result.
+func packageBatchApp(result *batchResult) {
+	version, err := batchAppVersion(result.dir)
+	if err == nil {
+		err = checkPackageVersion(version, &result.warnings)
+	}
+	if err == nil {
+		result.fpmPath, err = packageApp(result.dir, version, false, nil, &result.output)
+	}
+	result.err = err
+}
+
+// runBatchPackage packages every app found under batchDir, at most
+// --jobs at a time. A failing app does not stop the others; their output
+// is printed in order followed by a summary, and an error is returned if
+// any app failed. Warnings always go to stderr. With --porcelain only the
+// paths of the packages created are printed, and failures go to stderr.
+func runBatchPackage(cmd *cobra.Command, batchDir string) error {
+	absBatchDir, err := filepath.Abs(batchDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute batch path: %w", err)
+	}
+	apps, err := findBatchApps(absBatchDir)
+	if err != nil {
+		return err
+	}
+	if len(apps) == 0 {
+		return fmt.Errorf("no Frappe apps found in '%s'", absBatchDir)
+	}
+	jobs := packageJobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	results := make([]*batchResult, len(apps))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, appDir := range apps {
+		results[i] = &batchResult{dir: appDir}
+		wg.Add(1)
+		go func(result *batchResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			packageBatchApp(result)
+		}(results[i])
+	}
+	wg.Wait()
+
+	out := cmd.OutOrStdout()
+	var failed []string
+	for _, result := range results {
+		name := filepath.Base(result.dir)
+		if result.err != nil {
+			failed = append(failed, name)
+		}
+		cmd.ErrOrStderr().Write(result.warnings.Bytes())
+		switch {
+		case porcelain && result.err != nil:
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", name, result.err)
+		case porcelain:
+			fmt.Fprintln(out, result.fpmPath)
+		default:
+			out.Write(result.output.Bytes())
+			if result.err != nil {
+				fmt.Fprintf(out, "%s %s: %v\n", utils.Error(out, "Failed to package"), name, result.err)
+			}
+		}
+	}
+	if !porcelain {
+		fmt.Fprintf(out, "Packaged %d of %d apps\n", len(apps)-len(failed), len(apps))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to package %d app(s): %v", len(failed), failed)
+	}
+	return nil
+}
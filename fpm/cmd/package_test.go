@@ -1,11 +1,20 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+
+	"fpm/internal/archive"
+	"fpm/internal/metadata"
+
+	"github.com/spf13/pflag"
 )
 
 func TestValidateFrappeAppStructure(t *testing.T) {
@@ -320,6 +329,600 @@ func TestValidateFrappeAppStructure(t *testing.T) {
 	})
 }
 
+func TestValidateFrappeAppStructureModuleDirDiffersFromAppName(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// app_name = "hr" lives in frappe_hr/, alongside an unrelated package
+	moduleDir := filepath.Join(tmpDir, "frappe_hr")
+	otherDir := filepath.Join(tmpDir, "docs")
+	for _, dir := range []string{moduleDir, otherDir} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir %s: %v", dir, err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(moduleDir, "__init__.py"): "",
+		filepath.Join(moduleDir, "hooks.py"):    "app_name = \"hr\"\napp_title = \"HR\"\n",
+		filepath.Join(moduleDir, "modules.txt"): "HR",
+		filepath.Join(otherDir, "hooks.py"):     "app_name = \"docs\"\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	if err := validateFrappeAppStructure(tmpDir, "hr"); err != nil {
+		t.Errorf("Expected module located via hooks.py app_name to validate, got %v", err)
+	}
+
+	// No module declares this app_name, so the original error is kept
+	err := validateFrappeAppStructure(tmpDir, "payroll")
+	if err == nil {
+		t.Fatalf("Expected error for app_name not declared by any module, got nil")
+	}
+	expectedErrorPart := fmt.Sprintf("app directory '%s' not found", filepath.Join(tmpDir, "payroll"))
+	if !strings.Contains(err.Error(), expectedErrorPart) {
+		t.Errorf("Expected error message to contain '%s', but got '%s'", expectedErrorPart, err.Error())
+	}
+}
+
+// createValidFrappeApp creates a minimal valid Frappe app source at sourceDir/appName.
+func createValidFrappeApp(t *testing.T, sourceDir string, appName string) {
+	t.Helper()
+	innerAppDir := filepath.Join(sourceDir, appName)
+	if err := os.MkdirAll(innerAppDir, 0755); err != nil {
+		t.Fatalf("Failed to create inner app dir: %v", err)
+	}
+	files := map[string]string{
+		"__init__.py": "",
+		"hooks.py":    fmt.Sprintf("app_name = \"%s\"\n", appName),
+		"modules.txt": appName,
+	}
+	for fName, content := range files {
+		if err := os.WriteFile(filepath.Join(innerAppDir, fName), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", fName, err)
+		}
+	}
+}
+
+// runPackageCmd resets the package command's flags and runs it with args.
+func runPackageCmd(t *testing.T, args ...string) error {
+	t.Helper()
+	packageSourcePath, packageOutputPath, packageVersion = ".", ".", ""
+	packageOverwrite, packageKeepStaging, packageVerbose = false, false, false
+	packageStagingDir = ""
+	packageLabels = nil
+	packageRequireGit, packageAllowNonGit = false, false
+	packageStripSrc = false
+	packageStrictVer, packageAllowNonVer = false, false
+	packageStdout = false
+	packageMaxFiles, packageMaxSize = 0, 0
+	packageModuleDir = ""
+	packageSumCache = false
+	packageBatchDir, packageJobs = "", runtime.NumCPU()
+	packageFromBench = ""
+	packageAssets = "include"
+	packageCmd.Flags().VisitAll(func(f *pflag.Flag) { f.Changed = false })
+	rootCmd.SetArgs(append([]string{"package"}, args...))
+	return rootCmd.Execute()
+}
+
+// readPackagedMetadata returns the app_metadata.json embedded in an .fpm file.
+func readPackagedMetadata(t *testing.T, fpmPath string) *metadata.AppMetadata {
+	t.Helper()
+	extractDir := t.TempDir()
+	if err := archive.ExtractFPMArchive(fpmPath, extractDir); err != nil {
+		t.Fatalf("Failed to extract %s: %v", fpmPath, err)
+	}
+	meta, err := metadata.LoadAppMetadata(extractDir)
+	if err != nil {
+		t.Fatalf("Failed to load packaged metadata: %v", err)
+	}
+	return meta
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels([]string{"build=42", "ci_job=https://ci.example.com/job/7?x=1", "build=43"})
+	if err != nil {
+		t.Fatalf("parseLabels failed: %v", err)
+	}
+	expected := map[string]string{"build": "43", "ci_job": "https://ci.example.com/job/7?x=1"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Labels mismatch. Got %v, want %v", labels, expected)
+	}
+
+	for _, invalid := range []string{"novalue", "=value"} {
+		if _, err := parseLabels([]string{invalid}); err == nil {
+			t.Errorf("Expected error for invalid label '%s', got nil", invalid)
+		}
+	}
+}
+
+func TestPackageCommandLabels(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "label_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "label_app")
+
+	err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0",
+		"--label", "build=1234", "--label", "ticket=FPM-7")
+	if err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+
+	meta := readPackagedMetadata(t, filepath.Join(outputDir, "label_app-1.0.0.fpm"))
+	expected := map[string]string{"build": "1234", "ticket": "FPM-7"}
+	if !reflect.DeepEqual(meta.Labels, expected) {
+		t.Errorf("Packaged labels mismatch. Got %v, want %v", meta.Labels, expected)
+	}
+}
+
+func TestPackageCommandRequireCleanGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	sourceDir := filepath.Join(t.TempDir(), "git_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "git_app")
+
+	runGit := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", sourceDir}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	t.Run("non-git source", func(t *testing.T) {
+		err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "0.0.1", "--require-clean-git")
+		if err == nil || !strings.Contains(err.Error(), "is not a git repository") {
+			t.Errorf("Expected non-git error, got %v", err)
+		}
+		err = runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "0.0.1", "--require-clean-git", "--allow-non-git")
+		if err != nil {
+			t.Errorf("Expected --allow-non-git to permit packaging, got %v", err)
+		}
+	})
+
+	runGit("init", "-q")
+	runGit("-c", "user.name=fpm", "-c", "user.email=fpm@example.com", "add", "-A")
+	runGit("-c", "user.name=fpm", "-c", "user.email=fpm@example.com", "commit", "-q", "-m", "initial")
+
+	t.Run("clean tree", func(t *testing.T) {
+		err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--require-clean-git")
+		if err != nil {
+			t.Errorf("Expected clean tree to package, got %v", err)
+		}
+	})
+
+	t.Run("dirty tree", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(sourceDir, "git_app", "hooks.py"), []byte("app_name = \"git_app\"\n# edited\n"), 0644); err != nil {
+			t.Fatalf("Failed to modify hooks.py: %v", err)
+		}
+		err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.1", "--require-clean-git")
+		if err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+			t.Errorf("Expected dirty tree to be refused, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "git_app-1.0.1.fpm")); !os.IsNotExist(err) {
+			t.Errorf("Expected no package to be written for a dirty tree")
+		}
+	})
+}
+
+func TestPackageCommandRequireCleanGitSubdirectory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	repoDir := t.TempDir()
+	sourceDir := filepath.Join(repoDir, "apps", "sub_git_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "sub_git_app")
+	if err := os.WriteFile(filepath.Join(repoDir, "NOTES.md"), []byte("notes\n"), 0644); err != nil {
+		t.Fatalf("Failed to write NOTES.md: %v", err)
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}, {"commit", "-q", "-m", "initial"}} {
+		cmd := exec.Command("git", append([]string{"-C", repoDir, "-c", "user.name=fpm", "-c", "user.email=fpm@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	// Changes outside the app cannot affect the package and must not block it
+	if err := os.WriteFile(filepath.Join(repoDir, "NOTES.md"), []byte("edited\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify NOTES.md: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "untracked.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatalf("Failed to write untracked.txt: %v", err)
+	}
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--require-clean-git"); err != nil {
+		t.Errorf("Expected changes outside the source directory to be ignored, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(sourceDir, "sub_git_app", "api.py"), []byte("# new\n"), 0644); err != nil {
+		t.Fatalf("Failed to write api.py: %v", err)
+	}
+	err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.1", "--require-clean-git")
+	if err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Errorf("Expected changes inside the source directory to be refused, got %v", err)
+	}
+}
+
+func TestPackageCommandVersionValidation(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "ver_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "ver_app")
+
+	var stderr bytes.Buffer
+	rootCmd.SetErr(&stderr)
+	defer rootCmd.SetErr(nil)
+
+	t.Run("non-semver warns by default", func(t *testing.T) {
+		stderr.Reset()
+		if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0"); err != nil {
+			t.Fatalf("Expected non-semver version to package with a warning, got %v", err)
+		}
+		if !strings.Contains(stderr.String(), "Warning: version '1.0' is not a valid semantic version; use '1.0.0' instead") {
+			t.Errorf("Expected semver warning, got '%s'", stderr.String())
+		}
+	})
+
+	t.Run("non-semver fails with --strict-version", func(t *testing.T) {
+		err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.1", "--strict-version")
+		if err == nil || !strings.Contains(err.Error(), "use '1.1.0' instead") {
+			t.Errorf("Expected strict version error, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "ver_app-1.1.fpm")); !os.IsNotExist(err) {
+			t.Errorf("Expected no package for a rejected version")
+		}
+	})
+
+	t.Run("non-semver allowed silently", func(t *testing.T) {
+		stderr.Reset()
+		if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.2", "--allow-non-semver"); err != nil {
+			t.Fatalf("Expected --allow-non-semver to package, got %v", err)
+		}
+		if strings.Contains(stderr.String(), "Warning") {
+			t.Errorf("Expected no warning with --allow-non-semver, got '%s'", stderr.String())
+		}
+	})
+
+	t.Run("semver passes strict", func(t *testing.T) {
+		stderr.Reset()
+		if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--strict-version"); err != nil {
+			t.Fatalf("Expected semver version to package, got %v", err)
+		}
+		if stderr.Len() != 0 {
+			t.Errorf("Expected no warnings for a semver version, got '%s'", stderr.String())
+		}
+	})
+}
+
+func TestPackageCommandStdout(t *testing.T) {
+	for _, args := range [][]string{{"--stdout"}, {"--output-path", "-"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			sourceDir := filepath.Join(t.TempDir(), "stream_app")
+			createValidFrappeApp(t, sourceDir, "stream_app")
+
+			var stdout, stderr bytes.Buffer
+			rootCmd.SetOut(&stdout)
+			rootCmd.SetErr(&stderr)
+			defer rootCmd.SetOut(nil)
+			defer rootCmd.SetErr(nil)
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("Failed to get working directory: %v", err)
+			}
+			if err := runPackageCmd(t, append([]string{"--source", sourceDir, "--version", "1.0.0"}, args...)...); err != nil {
+				t.Fatalf("package command failed: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(cwd, "stream_app-1.0.0.fpm")); !os.IsNotExist(err) {
+				t.Errorf("Expected no .fpm file to be written to disk")
+			}
+			if !strings.Contains(stderr.String(), "Successfully packaged 'stream_app' version '1.0.0' to stdout") {
+				t.Errorf("Expected progress messages on stderr, got '%s'", stderr.String())
+			}
+
+			fpmPath := filepath.Join(t.TempDir(), "captured.fpm")
+			if err := os.WriteFile(fpmPath, stdout.Bytes(), 0644); err != nil {
+				t.Fatalf("Failed to write captured stdout: %v", err)
+			}
+			meta := readPackagedMetadata(t, fpmPath)
+			if meta.PackageName != "stream_app" || meta.PackageVersion != "1.0.0" {
+				t.Errorf("Unexpected metadata in streamed package: %+v", meta)
+			}
+		})
+	}
+}
+
+func TestPackageCommandFromInsideModuleDir(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "inner_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "inner_app")
+
+	moduleDir := filepath.Join(sourceDir, "inner_app")
+	if err := runPackageCmd(t, "--source", moduleDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed from inside the module directory: %v", err)
+	}
+
+	extractDir := t.TempDir()
+	if err := archive.ExtractFPMArchive(filepath.Join(outputDir, "inner_app-1.0.0.fpm"), extractDir); err != nil {
+		t.Fatalf("Failed to extract package: %v", err)
+	}
+	for _, rel := range []string{"app_metadata.json", "app_source/inner_app/hooks.py", "app_source/inner_app/modules.txt"} {
+		if _, err := os.Stat(filepath.Join(extractDir, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("Expected %s in package: %v", rel, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(extractDir, "app_source", "hooks.py")); !os.IsNotExist(err) {
+		t.Errorf("Expected hooks.py not to be packaged at the app_source root")
+	}
+}
+
+func TestPackageCommandLimits(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "limit_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "limit_app")
+
+	err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--max-files", "2")
+	if err == nil || !strings.Contains(err.Error(), "more than the limit of 2") {
+		t.Errorf("Expected --max-files error, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(outputDir, "limit_app-1.0.0.fpm")); !os.IsNotExist(statErr) {
+		t.Errorf("Expected no package to be written when a limit is exceeded")
+	}
+
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--max-files", "10", "--max-total-size", "1048576"); err != nil {
+		t.Errorf("Expected package within limits to succeed, got %v", err)
+	}
+}
+
+func TestPackageCommandModuleDir(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "renamed_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "renamed_app")
+
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--module-dir", "app"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+
+	extractDir := t.TempDir()
+	if err := archive.ExtractFPMArchive(filepath.Join(outputDir, "renamed_app-1.0.0.fpm"), extractDir); err != nil {
+		t.Fatalf("Failed to extract package: %v", err)
+	}
+	for _, rel := range []string{"app_source/app/hooks.py", "app_source/app/__init__.py", "app_source/app/modules.txt"} {
+		if _, err := os.Stat(filepath.Join(extractDir, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("Expected %s in package: %v", rel, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(extractDir, "app_source", "renamed_app")); !os.IsNotExist(err) {
+		t.Errorf("Expected original module directory to be renamed in the package")
+	}
+	appName, err := metadata.GetAppNameFromHooks(filepath.Join(extractDir, "app_source", "app", "hooks.py"))
+	if err != nil || appName != "renamed_app" {
+		t.Errorf("Expected hooks.py app_name to be unchanged, got '%s' (%v)", appName, err)
+	}
+
+	err = runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.1", "--module-dir", "../escape")
+	if err == nil || !strings.Contains(err.Error(), "invalid module directory name") {
+		t.Errorf("Expected invalid --module-dir to be rejected, got %v", err)
+	}
+}
+
+func TestPackageCommandNoColorWhenCaptured(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	sourceDir := filepath.Join(t.TempDir(), "plain_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "plain_app")
+
+	for _, extra := range [][]string{nil, {"--no-color"}} {
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&out)
+		args := append([]string{"--source", sourceDir, "--output-path", outputDir, "--version", "1.0", "--overwrite"}, extra...)
+		err := runPackageCmd(t, args...)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		if err != nil {
+			t.Fatalf("package command failed: %v", err)
+		}
+		if strings.Contains(out.String(), "\033[") {
+			t.Errorf("Expected no ANSI codes in captured output (args %v), got %q", extra, out.String())
+		}
+		if !strings.Contains(out.String(), "Warning:") || !strings.Contains(out.String(), "Successfully packaged:") {
+			t.Errorf("Expected warning and success messages, got %q", out.String())
+		}
+	}
+}
+
+func TestPackageCommandBatch(t *testing.T) {
+	batchDir := t.TempDir()
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, filepath.Join(batchDir, "first_app"), "first_app")
+	createValidFrappeApp(t, filepath.Join(batchDir, "second_app"), "second_app")
+	if err := os.MkdirAll(filepath.Join(batchDir, "not_an_app"), 0755); err != nil {
+		t.Fatalf("Failed to create non-app dir: %v", err)
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	err := runPackageCmd(t, "--batch", batchDir, "--output-path", outputDir, "--version", "1.0.0", "--jobs", "2")
+	rootCmd.SetOut(nil)
+	if err != nil {
+		t.Fatalf("batch package failed: %v\n%s", err, out.String())
+	}
+	for _, name := range []string{"first_app-1.0.0.fpm", "second_app-1.0.0.fpm"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("Expected %s to be produced: %v", name, err)
+		}
+	}
+	if !strings.Contains(out.String(), "Packaged 2 of 2 apps") {
+		t.Errorf("Expected batch summary, got %q", out.String())
+	}
+
+	// A broken app fails on its own without stopping the others
+	if err := os.Remove(filepath.Join(batchDir, "first_app", "first_app", "modules.txt")); err != nil {
+		t.Fatalf("Failed to remove modules.txt: %v", err)
+	}
+	out.Reset()
+	rootCmd.SetOut(&out)
+	err = runPackageCmd(t, "--batch", batchDir, "--output-path", outputDir, "--version", "1.0.1")
+	rootCmd.SetOut(nil)
+	if err == nil || !strings.Contains(err.Error(), "first_app") {
+		t.Errorf("Expected batch error naming first_app, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "second_app-1.0.1.fpm")); err != nil {
+		t.Errorf("Expected second_app to be packaged despite first_app failing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "first_app-1.0.1.fpm")); !os.IsNotExist(err) {
+		t.Errorf("Expected no package for the failing app")
+	}
+	if !strings.Contains(out.String(), "Packaged 1 of 2 apps") {
+		t.Errorf("Expected batch summary, got %q", out.String())
+	}
+
+	if err := runPackageCmd(t, "--batch", batchDir, "--stdout"); err == nil {
+		t.Errorf("Expected --batch with --stdout to be rejected")
+	}
+}
+
+func TestPackageCommandFromBench(t *testing.T) {
+	benchPath := createEmptyBench(t, "frappe\nbench_app\n")
+	outputDir := t.TempDir()
+
+	// A checkout whose directory name differs from the app it holds
+	sourceDir := filepath.Join(t.TempDir(), "bench-app-repo")
+	createValidFrappeApp(t, sourceDir, "bench_app")
+	if err := os.Symlink(sourceDir, filepath.Join(benchPath, "apps", "bench_app")); err != nil {
+		t.Fatalf("Failed to symlink app into bench: %v", err)
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--from-bench", benchPath)
+	rootCmd.SetOut(nil)
+	if err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Using app name 'bench_app' from the bench") {
+		t.Errorf("Expected the app name to be derived from the bench, got %q", out.String())
+	}
+	if meta := readPackagedMetadata(t, filepath.Join(outputDir, "bench_app-1.0.0.fpm")); meta.PackageName != "bench_app" {
+		t.Errorf("Expected packageName 'bench_app', got '%s'", meta.PackageName)
+	}
+
+	// A source whose inferred name is listed is confirmed as is
+	listedDir := filepath.Join(t.TempDir(), "bench_app")
+	createValidFrappeApp(t, listedDir, "bench_app")
+	out.Reset()
+	rootCmd.SetOut(&out)
+	err = runPackageCmd(t, "--source", listedDir, "--output-path", outputDir, "--version", "1.0.1", "--from-bench", benchPath)
+	rootCmd.SetOut(nil)
+	if err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "App name 'bench_app' confirmed") {
+		t.Errorf("Expected the app name to be confirmed, got %q", out.String())
+	}
+
+	otherDir := filepath.Join(t.TempDir(), "other_app")
+	createValidFrappeApp(t, otherDir, "other_app")
+	err = runPackageCmd(t, "--source", otherDir, "--output-path", outputDir, "--version", "1.0.0", "--from-bench", benchPath)
+	if err == nil || !strings.Contains(err.Error(), "is not listed in") {
+		t.Errorf("Expected an app missing from the bench to be rejected, got %v", err)
+	}
+}
+
+func TestPackageCommandAssets(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "assets_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "assets_app")
+	if err := os.MkdirAll(filepath.Join(sourceDir, "compiled_assets", "js"), 0755); err != nil {
+		t.Fatalf("Failed to create compiled_assets: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "compiled_assets", "js", "app.min.js"), []byte("min"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+
+	assetPackaged := func(fpmPath string) bool {
+		extractDir := t.TempDir()
+		if err := archive.ExtractFPMArchive(fpmPath, extractDir); err != nil {
+			t.Fatalf("Failed to extract %s: %v", fpmPath, err)
+		}
+		_, err := os.Stat(filepath.Join(extractDir, "compiled_assets", "js", "app.min.js"))
+		return err == nil
+	}
+
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	if !assetPackaged(filepath.Join(outputDir, "assets_app-1.0.0.fpm")) {
+		t.Errorf("Expected compiled_assets to be packaged by default")
+	}
+
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.1", "--assets", "exclude"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	if assetPackaged(filepath.Join(outputDir, "assets_app-1.0.1.fpm")) {
+		t.Errorf("Expected --assets exclude to leave out compiled_assets")
+	}
+
+	err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.2", "--assets", "auto")
+	if err == nil || !strings.Contains(err.Error(), "invalid --assets") {
+		t.Errorf("Expected an unknown --assets mode to be rejected, got %v", err)
+	}
+}
+
+func TestPackageCommandPorcelain(t *testing.T) {
+	defer func() { porcelain = false }()
+	sourceDir := filepath.Join(t.TempDir(), "script_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "script_app")
+
+	var stdout, stderr bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0", "--porcelain")
+	rootCmd.SetOut(nil)
+	rootCmd.SetErr(nil)
+	if err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	expected := filepath.Join(outputDir, "script_app-1.0.fpm") + "\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected only the package path on stdout, got %q, want %q", stdout.String(), expected)
+	}
+	if !strings.Contains(stderr.String(), "not a valid semantic version") {
+		t.Errorf("Expected warnings to still go to stderr, got %q", stderr.String())
+	}
+}
+
+func TestPackageCommandBatchPorcelainWarnings(t *testing.T) {
+	defer func() { porcelain = false }()
+	batchDir := t.TempDir()
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, filepath.Join(batchDir, "warn_app"), "warn_app")
+
+	var stdout, stderr bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	err := runPackageCmd(t, "--batch", batchDir, "--output-path", outputDir, "--version", "1.0", "--porcelain")
+	rootCmd.SetOut(nil)
+	rootCmd.SetErr(nil)
+	if err != nil {
+		t.Fatalf("batch package failed: %v", err)
+	}
+	expected := filepath.Join(outputDir, "warn_app-1.0.fpm") + "\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected only the package path on stdout, got %q, want %q", stdout.String(), expected)
+	}
+	if !strings.Contains(stderr.String(), "not a valid semantic version") {
+		t.Errorf("Expected the version warning on stderr, got %q", stderr.String())
+	}
+}
+
 // Note: This test file assumes that `validateFrappeAppStructure` is in the `cmd` package.
 // If it's in a different package, the import path for `validateFrappeAppStructure` would need adjustment,
 // but since they are in the same package `cmd`, direct calls are fine.
@@ -334,3 +937,34 @@ func TestValidateFrappeAppStructure(t *testing.T) {
 // To run with coverage:
 // go test -coverprofile=coverage.out
 // go tool cover -html=coverage.out
+
+func TestResolveStagingDir(t *testing.T) {
+	t.Setenv(stagingDirEnvVar, "")
+
+	dir, err := resolveStagingDir("")
+	if err != nil {
+		t.Fatalf("resolveStagingDir failed: %v", err)
+	}
+	if dir != "" {
+		t.Errorf("Expected empty staging dir by default, got '%s'", dir)
+	}
+
+	envDir := t.TempDir()
+	t.Setenv(stagingDirEnvVar, envDir)
+	dir, err = resolveStagingDir("")
+	if err != nil {
+		t.Fatalf("resolveStagingDir failed: %v", err)
+	}
+	if dir != envDir {
+		t.Errorf("Expected staging dir from %s to be '%s', got '%s'", stagingDirEnvVar, envDir, dir)
+	}
+
+	flagDir := t.TempDir()
+	dir, err = resolveStagingDir(flagDir)
+	if err != nil {
+		t.Fatalf("resolveStagingDir failed: %v", err)
+	}
+	if dir != flagDir {
+		t.Errorf("Expected --staging-dir to take precedence, got '%s'", dir)
+	}
+}
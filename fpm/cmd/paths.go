@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var pathsCmd = &cobra.Command{
+	Use:   "paths",
+	Short: "Print the filesystem locations FPM will use",
+	Long: `Prints the resolved locations FPM reads from and writes to in the current
+environment, taking environment variable overrides into account.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+
+		stagingDir, err := resolveStagingDir("")
+		if err != nil {
+			return err
+		}
+		if stagingDir == "" {
+			fmt.Fprintf(out, "Staging directory: %s (system temp directory)\n", os.TempDir())
+		} else {
+			fmt.Fprintf(out, "Staging directory: %s (from $%s)\n", stagingDir, stagingDirEnvVar)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pathsCmd)
+}
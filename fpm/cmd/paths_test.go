@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// runRootCmd runs the root command with args and returns its captured output.
+func runRootCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetErr(nil)
+	rootCmd.SetArgs(args)
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+func TestPathsCommand(t *testing.T) {
+	t.Setenv(stagingDirEnvVar, "")
+	out, err := runRootCmd(t, "paths")
+	if err != nil {
+		t.Fatalf("paths command failed: %v", err)
+	}
+	expected := "Staging directory: " + os.TempDir() + " (system temp directory)"
+	if !strings.Contains(out, expected) {
+		t.Errorf("Expected output to contain '%s', got '%s'", expected, out)
+	}
+
+	stagingDir := t.TempDir()
+	t.Setenv(stagingDirEnvVar, stagingDir)
+	out, err = runRootCmd(t, "paths")
+	if err != nil {
+		t.Fatalf("paths command failed: %v", err)
+	}
+	expected = "Staging directory: " + stagingDir + " (from $" + stagingDirEnvVar + ")"
+	if !strings.Contains(out, expected) {
+		t.Errorf("Expected output to contain '%s', got '%s'", expected, out)
+	}
+}
@@ -3,16 +3,30 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+
+	"fpm/internal/metadata"
+	"fpm/internal/utils"
 
 	"github.com/spf13/cobra"
 )
 
+// porcelain selects minimal, stable, line-oriented output for scripts.
+var porcelain bool
+
 var rootCmd = &cobra.Command{
 	Use:   "fpm",
 	Short: "Frappe Package Manager (FPM) CLI",
 	Long: `FPM is a command-line interface to manage Frappe applications,
 providing package creation, installation, and repository management
 to streamline Frappe app deployment.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := metadata.ValidateFileName(metadata.FileName); err != nil {
+			return fmt.Errorf("--metadata-file: %w", err)
+		}
+		return nil
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -21,18 +35,48 @@ to streamline Frappe app deployment.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	stop := handleInterrupts()
+	defer stop()
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
+// handleInterrupts installs a SIGINT/SIGTERM handler that removes any
+// in-progress staging directories and partial files before exiting.
+// The returned function stops the handler.
+func handleInterrupts() (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-sigCh:
+			fmt.Fprintf(os.Stderr, "\nReceived %s, cleaning up...\n", sig)
+			utils.RunCleanups()
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
+
 func init() {
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.fpm.yaml)")
+	rootCmd.PersistentFlags().StringVar(&metadata.FileName, "metadata-file", metadata.DefaultFileName, "Name of the metadata file in app sources and packages")
+	rootCmd.PersistentFlags().BoolVar(&porcelain, "porcelain", false, "Print only minimal, stable, machine-parseable output (package prints the .fpm path, install --check the app==version)")
+	rootCmd.PersistentFlags().BoolVar(&utils.NoColor, "no-color", false, "Disable colored output (also disabled when NO_COLOR is set or output is not a terminal)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"fpm/internal/archive"
+	"fpm/internal/metadata"
+	"fpm/internal/sbom"
+	"fpm/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var sbomCmd = &cobra.Command{
+	Use:   "sbom <path.fpm>",
+	Short: "Print a CycloneDX SBOM for an .fpm package",
+	Long: `Prints a CycloneDX JSON software bill of materials for an .fpm package,
+built from its app_metadata.json: the app with its version, the SHA-256 of
+the package file, its content checksum and labels, and the apps it declares
+as dependencies.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fpmPath := args[0]
+		packageSHA256, err := utils.CalculateFileChecksum(fpmPath)
+		if err != nil {
+			return fmt.Errorf("failed to checksum '%s': %w", fpmPath, err)
+		}
+
+		meta, err := archive.ReadPackageMetadata(fpmPath)
+		if err != nil {
+			return err
+		}
+		if meta.PackageName == "" {
+			return fmt.Errorf("package '%s' has no packageName in %s", fpmPath, metadata.FileName)
+		}
+
+		doc := sbom.FromMetadata(meta, packageSHA256, time.Now())
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sbomCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"fpm/internal/sbom"
+	"fpm/internal/utils"
+)
+
+func TestSBOMCommand(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "sbom_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "sbom_app")
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "2.0.0", "--label", "build=7"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	fpmPath := filepath.Join(outputDir, "sbom_app-2.0.0.fpm")
+
+	out, err := runRootCmd(t, "sbom", fpmPath)
+	if err != nil {
+		t.Fatalf("sbom command failed: %v", err)
+	}
+	var doc sbom.Document
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("sbom output is not valid JSON: %v\n%s", err, out)
+	}
+
+	app := doc.Metadata.Component
+	if doc.BOMFormat != "CycloneDX" || app.Name != "sbom_app" || app.Version != "2.0.0" {
+		t.Errorf("Unexpected SBOM: %+v", doc)
+	}
+	fileSum, err := utils.CalculateFileChecksum(fpmPath)
+	if err != nil {
+		t.Fatalf("Failed to checksum package: %v", err)
+	}
+	if len(app.Hashes) != 1 || app.Hashes[0].Content != fileSum {
+		t.Errorf("Expected package SHA-256 %s, got %+v", fileSum, app.Hashes)
+	}
+	meta := readPackagedMetadata(t, fpmPath)
+	expectedProps := map[string]string{"fpm:contentChecksum": meta.ContentChecksum, "fpm:label:build": "7"}
+	for _, prop := range app.Properties {
+		if expectedProps[prop.Name] == prop.Value {
+			delete(expectedProps, prop.Name)
+		}
+	}
+	if len(expectedProps) != 0 {
+		t.Errorf("Missing or wrong SBOM properties %v in %+v", expectedProps, app.Properties)
+	}
+}
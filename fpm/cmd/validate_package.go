@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fpm/internal/archive"
+	"fpm/internal/metadata"
+	"fpm/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// validatePackage checks the .fpm package at fpmPath and returns a
+// description of each problem found. The embedded app_metadata.json must
+// parse and declare a package name, version and content checksum, the app
+// module must be present with a valid structure, and the content checksum
+// must match the packaged files.
+func validatePackage(fpmPath string) ([]string, error) {
+	extractDir, cleanup, err := extractToTemp(fpmPath)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	if _, err := os.Stat(filepath.Join(extractDir, metadata.FileName)); err != nil {
+		return []string{fmt.Sprintf("%s is missing", metadata.FileName)}, nil
+	}
+	meta, err := metadata.LoadAppMetadata(extractDir)
+	if err != nil {
+		return []string{fmt.Sprintf("%s is not valid JSON: %v", metadata.FileName, err)}, nil
+	}
+
+	var problems []string
+	required := []struct {
+		field string
+		value string
+	}{
+		{"packageName", meta.PackageName},
+		{"packageVersion", meta.PackageVersion},
+		{"contentChecksum", meta.ContentChecksum},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			problems = append(problems, fmt.Sprintf("%s: required field '%s' is missing or empty", metadata.FileName, r.field))
+		}
+	}
+
+	if meta.PackageName != "" {
+		if err := validateFrappeAppStructure(filepath.Join(extractDir, "app_source"), meta.PackageName); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if meta.ContentChecksum != "" {
+		checksum, err := archive.ContentChecksum(extractDir)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != meta.ContentChecksum {
+			problems = append(problems, fmt.Sprintf("content checksum mismatch: %s records %s, package contents hash to %s", metadata.FileName, meta.ContentChecksum, checksum))
+		}
+	}
+	return problems, nil
+}
+
+var validatePackageCmd = &cobra.Command{
+	Use:   "validate-package <path.fpm>",
+	Short: "Validate the metadata and contents of an .fpm file",
+	Long: `Extracts an .fpm package to a temporary directory and checks that its
+app_metadata.json declares the required fields (packageName, packageVersion,
+contentChecksum), that the app module directory has a valid Frappe structure,
+and that the recorded content checksum matches the packaged files.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems, err := validatePackage(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to validate '%s': %w", args[0], err)
+		}
+		out := cmd.OutOrStdout()
+		if len(problems) == 0 {
+			fmt.Fprintln(out, utils.Success(out, fmt.Sprintf("Package '%s' is valid", args[0])))
+			return nil
+		}
+		for _, problem := range problems {
+			fmt.Fprintf(out, "  %s\n", utils.Error(out, problem))
+		}
+		return fmt.Errorf("package '%s' is invalid: %d problem(s) found", args[0], len(problems))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validatePackageCmd)
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fpm/internal/metadata"
+)
+
+// rewritePackage copies the .fpm at srcPath to a new file, passing each
+// entry's content through edit, and returns the new file's path.
+func rewritePackage(t *testing.T, srcPath string, edit func(name string, content []byte) []byte) string {
+	t.Helper()
+	reader, err := zip.OpenReader(srcPath)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", srcPath, err)
+	}
+	defer reader.Close()
+
+	dstPath := filepath.Join(t.TempDir(), filepath.Base(srcPath))
+	dstFile, err := os.Create(dstPath)
+	if err != nil {
+		t.Fatalf("Failed to create %s: %v", dstPath, err)
+	}
+	defer dstFile.Close()
+	writer := zip.NewWriter(dstFile)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open entry %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("Failed to read entry %s: %v", f.Name, err)
+		}
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method})
+		if err != nil {
+			t.Fatalf("Failed to create entry %s: %v", f.Name, err)
+		}
+		if _, err := w.Write(edit(f.Name, content)); err != nil {
+			t.Fatalf("Failed to write entry %s: %v", f.Name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close %s: %v", dstPath, err)
+	}
+	return dstPath
+}
+
+// addZipEntry appends an entry named name with content to the zip at zipPath.
+func addZipEntry(t *testing.T, zipPath string, name string, content []byte) {
+	t.Helper()
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", zipPath, err)
+	}
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, f := range reader.File {
+		if err := writer.Copy(f); err != nil {
+			t.Fatalf("Failed to copy entry %s: %v", f.Name, err)
+		}
+	}
+	reader.Close()
+	w, err := writer.Create(name)
+	if err != nil {
+		t.Fatalf("Failed to create entry %s: %v", name, err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("Failed to write entry %s: %v", name, err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	if err := os.WriteFile(zipPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", zipPath, err)
+	}
+}
+
+func TestValidatePackageCommand(t *testing.T) {
+	sourceDir := filepath.Join(t.TempDir(), "valid_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "valid_app")
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	fpmPath := filepath.Join(outputDir, "valid_app-1.0.0.fpm")
+
+	out, err := runRootCmd(t, "validate-package", fpmPath)
+	if err != nil {
+		t.Fatalf("Expected valid package to pass validation, got %v: %s", err, out)
+	}
+
+	missingVersion := rewritePackage(t, fpmPath, func(name string, content []byte) []byte {
+		if name != "app_metadata.json" {
+			return content
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(content, &fields); err != nil {
+			t.Fatalf("Failed to parse app_metadata.json: %v", err)
+		}
+		delete(fields, "packageVersion")
+		edited, _ := json.Marshal(fields)
+		return edited
+	})
+	out, err = runRootCmd(t, "validate-package", missingVersion)
+	if err == nil {
+		t.Fatalf("Expected validation to fail for metadata without packageVersion")
+	}
+	if !strings.Contains(out, "required field 'packageVersion' is missing or empty") {
+		t.Errorf("Expected the missing field to be reported, got '%s'", out)
+	}
+
+	tampered := rewritePackage(t, fpmPath, func(name string, content []byte) []byte {
+		if name == "app_source/valid_app/hooks.py" {
+			return append(content, []byte("doc_events = {}\n")...)
+		}
+		return content
+	})
+	out, err = runRootCmd(t, "validate-package", tampered)
+	if err == nil || !strings.Contains(out, "content checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch for tampered contents, got %v: '%s'", err, out)
+	}
+
+	withManifest := rewritePackage(t, fpmPath, func(name string, content []byte) []byte { return content })
+	addZipEntry(t, withManifest, "_manifest.json", []byte(`{"files": []}`))
+	out, err = runRootCmd(t, "validate-package", withManifest)
+	if err != nil {
+		t.Errorf("Expected a package with a _manifest.json to pass validation, got %v: '%s'", err, out)
+	}
+}
+
+func TestMetadataFileFlag(t *testing.T) {
+	defer func() { metadata.FileName = metadata.DefaultFileName }()
+	sourceDir := filepath.Join(t.TempDir(), "renamed_meta_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "renamed_meta_app")
+
+	if err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--metadata-file", "fpm.json"); err != nil {
+		t.Fatalf("package command failed: %v", err)
+	}
+	fpmPath := filepath.Join(outputDir, "renamed_meta_app-1.0.0.fpm")
+	reader, err := zip.OpenReader(fpmPath)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", fpmPath, err)
+	}
+	names := make(map[string]bool)
+	for _, f := range reader.File {
+		names[f.Name] = true
+	}
+	reader.Close()
+	if !names["fpm.json"] || names["app_metadata.json"] {
+		t.Fatalf("Expected the metadata to be written as fpm.json only, got entries %v", names)
+	}
+
+	if out, err := runRootCmd(t, "validate-package", fpmPath, "--metadata-file", "fpm.json"); err != nil {
+		t.Errorf("Expected validate-package to honor --metadata-file, got %v: %s", err, out)
+	}
+	if out, err := runRootCmd(t, "install", fpmPath, "--check", "--bench-path", createEmptyBench(t, "frappe\n"), "--metadata-file", "fpm.json"); err != nil || !strings.Contains(out, "'renamed_meta_app' version '1.0.0'") {
+		t.Errorf("Expected install --check to honor --metadata-file, got %v: %s", err, out)
+	}
+	if out, err := runRootCmd(t, "info", fpmPath, "--metadata-file", "fpm.json"); err != nil || !strings.Contains(out, "Package: renamed_meta_app\n") {
+		t.Errorf("Expected info to honor --metadata-file, got %v: %s", err, out)
+	}
+
+	out, err := runRootCmd(t, "validate-package", fpmPath, "--metadata-file", metadata.DefaultFileName)
+	if err == nil || !strings.Contains(out, "app_metadata.json is missing") {
+		t.Errorf("Expected validation with the default name to report the file missing, got %v: %s", err, out)
+	}
+}
+
+func TestMetadataFileFlagRejectsPaths(t *testing.T) {
+	defer func() { metadata.FileName = metadata.DefaultFileName }()
+	sourceDir := filepath.Join(t.TempDir(), "escape_meta_app")
+	outputDir := t.TempDir()
+	createValidFrappeApp(t, sourceDir, "escape_meta_app")
+
+	invalid := []string{"../x.json", "sub/meta.json", "..", ""}
+	// Names fpm uses for other parts of the package would be overwritten
+	invalid = append(invalid, "app_source", "compiled_assets", "requirements.txt", "package.json", "install_hooks.py", ".fpmignore", "_manifest.json", "_source.json")
+	for _, name := range invalid {
+		err := runPackageCmd(t, "--source", sourceDir, "--output-path", outputDir, "--version", "1.0.0", "--metadata-file", name, "--overwrite")
+		if err == nil || !strings.Contains(err.Error(), "invalid metadata file name") {
+			t.Errorf("Expected --metadata-file '%s' to be rejected, got %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "escape_meta_app-1.0.0.fpm")); !os.IsNotExist(err) {
+		t.Errorf("Expected no package to be written with an invalid --metadata-file")
+	}
+}
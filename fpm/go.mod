@@ -3,11 +3,10 @@ module fpm
 go 1.22.2
 
 require (
+	github.com/Masterminds/semver/v3 v3.3.1
 	github.com/sabhiram/go-gitignore v0.0.0-20210923224102-525f6e181f06
 	github.com/spf13/cobra v1.9.1
+	github.com/spf13/pflag v1.0.6
 )
 
-require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.6 // indirect
-)
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
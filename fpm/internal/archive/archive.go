@@ -8,9 +8,9 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"fpm/internal/metadata" // Import the metadata package
-
-	"github.com/sabhiram/go-gitignore" // For .fpmignore
+	"fpm/internal/utils"
 )
 
 var defaultIgnorePatterns = []string{
@@ -27,52 +27,125 @@ var defaultIgnorePatterns = []string{
 	"*.log",
 }
 
+// ArchiveOptions holds optional settings for CreateFPMArchiveWithOptions.
+// The zero value reproduces the behaviour of CreateFPMArchive.
+type ArchiveOptions struct {
+	// StagingDir is the parent directory in which the temporary staging
+	// directory is created. If empty, the system temp directory is used.
+	StagingDir string
+	// KeepStaging leaves the staging directory on disk after the archive
+	// has been written, which is useful for inspecting what was packaged.
+	KeepStaging bool
+	// StripSources drops raw .py and .js files from app_source, keeping
+	// only the loaders Frappe needs (__init__.py, hooks.py, modules.txt).
+	// compiled_assets, metadata and other non-source files are unaffected.
+	StripSources bool
+	// Output, if set, receives the .fpm archive bytes instead of a file
+	// being written to outputPath. ArchivePath is left empty in that case.
+	Output io.Writer
+	// ChecksumOnly stages the package and computes its content checksum
+	// without writing app_metadata.json or an archive. version may be empty.
+	ChecksumOnly bool
+	// MaxFiles and MaxTotalSize, if positive, cap the number of files and
+	// the total size in bytes of the staged package contents (excluding
+	// app_metadata.json). Packaging fails when either is exceeded.
+	MaxFiles     int
+	MaxTotalSize int64
+	// RenameModuleDir, if set, is the name the app module directory gets
+	// inside app_source. ModuleDir names that directory relative to the app
+	// source and defaults to the package name. Nothing else is changed.
+	RenameModuleDir string
+	ModuleDir       string
+	// ChecksumCachePath, if set, is a per-file checksum cache used to avoid
+	// re-hashing unchanged files when computing the content checksum.
+	// Staged files keep their source modification times so entries stay
+	// valid between runs.
+	ChecksumCachePath string
+	// ExcludeCompiledAssets leaves the compiled_assets directory out of
+	// the package, for packages whose assets are built where they are
+	// installed.
+	ExcludeCompiledAssets bool
+}
+
+// ArchiveResult describes the outcome of CreateFPMArchiveWithOptions.
+type ArchiveResult struct {
+	// ArchivePath is the path of the created .fpm file.
+	ArchivePath string
+	// StagingPath is the staging directory used to assemble the package.
+	// It only exists after the call returns if KeepStaging was set.
+	StagingPath string
+	// FileDecisions lists, in walk order, every source path that was
+	// considered for the package and whether it was included.
+	FileDecisions []FileDecision
+	// ContentChecksum is the checksum of the staged package contents,
+	// excluding app_metadata.json, as recorded in the package metadata.
+	ContentChecksum string
+}
+
 // CreateFPMArchive creates an .fpm package from the app source.
 // appSourcePath: Path to the Frappe app's source directory.
 // outputPath: Directory where the .fpm file should be saved.
 // meta: The AppMetadata for the package.
 // version: The specific version string for this package.
 func CreateFPMArchive(appSourcePath string, outputPath string, meta *metadata.AppMetadata, version string) error {
+	_, err := CreateFPMArchiveWithOptions(appSourcePath, outputPath, meta, version, ArchiveOptions{})
+	return err
+}
+
+// CreateFPMArchiveWithOptions behaves like CreateFPMArchive but accepts
+// additional options controlling how the package is assembled.
+func CreateFPMArchiveWithOptions(appSourcePath string, outputPath string, meta *metadata.AppMetadata, version string, opts ArchiveOptions) (*ArchiveResult, error) {
 	if meta == nil {
-		return errors.New("metadata cannot be nil")
+		return nil, errors.New("metadata cannot be nil")
 	}
 	if meta.PackageName == "" {
-		return errors.New("package name in metadata cannot be empty")
+		return nil, errors.New("package name in metadata cannot be empty")
 	}
-	if version == "" {
-		return errors.New("version cannot be empty")
+	if version == "" && !opts.ChecksumOnly {
+		return nil, errors.New("version cannot be empty")
 	}
 
 	// Ensure appSourcePath is absolute and clean
 	absAppSourcePath, err := filepath.Abs(appSourcePath)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path for app source: %w", err)
+		return nil, fmt.Errorf("failed to get absolute path for app source: %w", err)
 	}
 
-	// Create a temporary staging directory
-	stagingDir, err := os.MkdirTemp("", "fpm-staging-"+meta.PackageName+"-")
+	// Create a temporary staging directory, under opts.StagingDir if given
+	if opts.StagingDir != "" {
+		if err := os.MkdirAll(opts.StagingDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create staging parent directory %s: %w", opts.StagingDir, err)
+		}
+	}
+	stagingDir, err := os.MkdirTemp(opts.StagingDir, "fpm-staging-"+meta.PackageName+"-")
 	if err != nil {
-		return fmt.Errorf("failed to create staging directory: %w", err)
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	if !opts.KeepStaging {
+		defer os.RemoveAll(stagingDir)
+	}
+	// If the process is interrupted while packaging, the staging directory
+	// is incomplete and is removed even when KeepStaging is set.
+	unregisterStagingCleanup := utils.RegisterCleanup(func() { os.RemoveAll(stagingDir) })
+	defer unregisterStagingCleanup()
+
+	result := &ArchiveResult{StagingPath: stagingDir}
+	record := func(d FileDecision) {
+		result.FileDecisions = append(result.FileDecisions, d)
 	}
-	defer os.RemoveAll(stagingDir)
 
 	// --- Prepare .fpmignore ---
-	ignoreFilePath := filepath.Join(absAppSourcePath, ".fpmignore")
-	var ignorer *ignore.GitIgnore // Changed gitignore to ignore
-	if _, err := os.Stat(ignoreFilePath); err == nil {
-		ignorer, err = ignore.CompileIgnoreFile(ignoreFilePath) // Changed gitignore to ignore
-		if err != nil {
-			return fmt.Errorf("failed to compile .fpmignore: %w", err)
-		}
-	} else {
-		// Use default patterns if .fpmignore doesn't exist
-		ignorer = ignore.CompileIgnoreLines(defaultIgnorePatterns...) // Changed gitignore to ignore
+	// Nested .fpmignore files are loaded into the tree as the walk reaches them
+	rootRules, err := loadRootIgnoreRules(absAppSourcePath)
+	if err != nil {
+		return nil, err
 	}
+	ignorer := newIgnoreTree(absAppSourcePath, rootRules)
 
 	// --- Copy app source files ---
 	appSourceStagePath := filepath.Join(stagingDir, "app_source")
 	if err := os.MkdirAll(appSourceStagePath, 0755); err != nil {
-		return fmt.Errorf("failed to create app_source in staging: %w", err)
+		return nil, fmt.Errorf("failed to create app_source in staging: %w", err)
 	}
 
 	err = filepath.WalkDir(absAppSourcePath, func(path string, d fs.DirEntry, err error) error {
@@ -94,40 +167,70 @@ func CreateFPMArchive(appSourcePath string, outputPath string, meta *metadata.Ap
 		// These checks are for items at the root of absAppSourcePath
 		if filepath.Dir(relPath) == "." { // Check if it's a root item
 			switch relPath {
-			case "compiled_assets", "requirements.txt", "package.json", "install_hooks.py", "app_metadata.json", ".fpmignore":
+			case "compiled_assets", "requirements.txt", "package.json", "install_hooks.py":
+				// Copied to the archive root below, which records their inclusion
 				if d.IsDir() {
 					return filepath.SkipDir
 				}
 				return nil // Skip this file
+			case metadata.FileName, ".fpmignore":
+				record(FileDecision{Path: filepath.ToSlash(relPath), Source: ExcludedByRootSkip})
+				return nil // Skip this file
 			}
 		}
 
-		// Check against ignorer (relative to appSourcePath)
-		// go-gitignore expects paths relative to the .fpmignore file's location (absAppSourcePath)
-		if ignorer.MatchesPath(relPath) {
+		// Nested .fpmignore files are applied, not packaged
+		if !d.IsDir() && d.Name() == ignoreFileName {
+			record(FileDecision{Path: filepath.ToSlash(relPath), Source: ExcludedByRootSkip})
+			return nil
+		}
+
+		// Check against ignorer (relative to appSourcePath); rules from
+		// nested .fpmignore files are matched relative to their directory
+		if ignored, decision := ignorer.match(relPath); ignored {
+			decision.Path = filepath.ToSlash(relPath)
 			if d.IsDir() {
+				decision.Path += "/"
+				record(decision)
 				return filepath.SkipDir // Skip ignored directories
 			}
+			record(decision)
 			return nil // Skip ignored files
 		}
 
 		targetPath := filepath.Join(appSourceStagePath, relPath)
 		if d.IsDir() {
+			if err := ignorer.loadDir(relPath); err != nil {
+				return err
+			}
 			return os.MkdirAll(targetPath, 0755) // Use fixed permissions for staging directories
 		}
 
+		if opts.StripSources && isStrippedSource(relPath) {
+			record(FileDecision{Path: filepath.ToSlash(relPath), Source: ExcludedByStripSources})
+			return nil
+		}
+
+		record(FileDecision{Path: filepath.ToSlash(relPath), Included: true})
+		if d.Type()&fs.ModeSymlink != 0 {
+			return copySymlink(path, targetPath, absAppSourcePath)
+		}
 		return copyFile(path, targetPath) // copyFile will handle file permissions
 	})
 	if err != nil {
-		return fmt.Errorf("failed to walk and copy app source directory: %w", err)
+		return nil, fmt.Errorf("failed to walk and copy app source directory: %w", err)
 	}
 
 
-	// --- Save app_metadata.json ---
-	// Ensure version in metadata is the one passed to this function
-	meta.PackageVersion = version
-	if err := metadata.SaveAppMetadata(stagingDir, meta); err != nil { // Save at the root of staging
-		return fmt.Errorf("failed to save app_metadata.json: %w", err)
+	// --- Rename the app module directory ---
+	if opts.RenameModuleDir != "" {
+		moduleDir := opts.ModuleDir
+		if moduleDir == "" {
+			moduleDir = meta.PackageName
+		}
+		if err := renameModuleDir(appSourceStagePath, moduleDir, opts.RenameModuleDir); err != nil {
+			return nil, err
+		}
 	}
 
 	// --- Copy other standard files (requirements.txt, package.json, install_hooks.py) ---
@@ -136,39 +239,118 @@ func CreateFPMArchive(appSourcePath string, outputPath string, meta *metadata.Ap
 		srcFile := filepath.Join(absAppSourcePath, fName)
 		if _, err := os.Stat(srcFile); err == nil { // if file exists
 			if err := copyFile(srcFile, filepath.Join(stagingDir, fName)); err != nil {
-				return fmt.Errorf("failed to copy %s: %w", fName, err)
+				return nil, fmt.Errorf("failed to copy %s: %w", fName, err)
 			}
+			record(FileDecision{Path: fName, Included: true})
 		}
 	}
 
 	// --- Handle compiled_assets ---
 	compiledAssetsPath := filepath.Join(absAppSourcePath, "compiled_assets")
-	if _, err := os.Stat(compiledAssetsPath); err == nil { // if dir exists
+	if _, err := os.Stat(compiledAssetsPath); err == nil && opts.ExcludeCompiledAssets {
+		record(FileDecision{Path: "compiled_assets/", Source: ExcludedByAssets})
+	} else if err == nil { // if dir exists
 		stagedCompiledAssetsPath := filepath.Join(stagingDir, "compiled_assets")
-		if err := copyDir(compiledAssetsPath, stagedCompiledAssetsPath, ignorer, absAppSourcePath); err != nil {
-			return fmt.Errorf("failed to copy compiled_assets: %w", err)
+		if err := copyDir(compiledAssetsPath, stagedCompiledAssetsPath, ignorer, absAppSourcePath, record); err != nil {
+			return nil, fmt.Errorf("failed to copy compiled_assets: %w", err)
 		}
 	}
 
+	// --- Enforce package size limits ---
+	if err := checkStagingLimits(stagingDir, opts.MaxFiles, opts.MaxTotalSize); err != nil {
+		return nil, err
+	}
+
+	// --- Compute the content checksum over everything except ChecksumExcludedFiles ---
+	var checksumCache *utils.ChecksumCache
+	if opts.ChecksumCachePath != "" {
+		checksumCache = utils.LoadChecksumCache(opts.ChecksumCachePath)
+	}
+	contentChecksum, err := checksumPackageDir(stagingDir, checksumCache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute content checksum: %w", err)
+	}
+	if checksumCache != nil {
+		if err := checksumCache.Save(); err != nil {
+			return nil, fmt.Errorf("failed to save checksum cache: %w", err)
+		}
+	}
+	result.ContentChecksum = contentChecksum
+	if opts.ChecksumOnly {
+		return result, nil
+	}
+
+	// --- Save app_metadata.json ---
+	// Ensure version in metadata is the one passed to this function
+	meta.PackageVersion = version
+	meta.ContentChecksum = contentChecksum
+	if err := metadata.SaveAppMetadata(stagingDir, meta); err != nil { // Save at the root of staging
+		return nil, fmt.Errorf("failed to save %s: %w", metadata.FileName, err)
+	}
+
+	// --- Write the .fpm ZIP archive to opts.Output, if given ---
+	if opts.Output != nil {
+		if err := writeStagingZip(stagingDir, opts.Output); err != nil {
+			return nil, fmt.Errorf("failed to create zip archive: %w", err)
+		}
+		return result, nil
+	}
+
 	// --- Create the .fpm ZIP archive ---
 	outputFilename := fmt.Sprintf("%s-%s.fpm", meta.PackageName, version)
 	outputFilePath := filepath.Join(outputPath, outputFilename)
 
 	// Ensure output directory exists
 	if err := os.MkdirAll(outputPath, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory %s: %w", outputPath, err)
+		return nil, fmt.Errorf("failed to create output directory %s: %w", outputPath, err)
 	}
 
 	archiveFile, err := os.Create(outputFilePath)
 	if err != nil {
-		return fmt.Errorf("failed to create archive file %s: %w", outputFilePath, err)
+		return nil, fmt.Errorf("failed to create archive file %s: %w", outputFilePath, err)
 	}
 	defer archiveFile.Close()
+	unregisterArchiveCleanup := utils.RegisterCleanup(func() { os.Remove(outputFilePath) })
+	defer unregisterArchiveCleanup()
+
+	if err := writeStagingZip(stagingDir, archiveFile); err != nil {
+		// Attempt to remove partially created archive on error
+		os.Remove(outputFilePath)
+		return nil, fmt.Errorf("failed to create zip archive: %w", err)
+	}
 
-	zipWriter := zip.NewWriter(archiveFile)
-	defer zipWriter.Close()
+	result.ArchivePath = outputFilePath
+	return result, nil
+}
 
-	err = filepath.WalkDir(stagingDir, func(path string, d fs.DirEntry, err error) error {
+// renameModuleDir renames the staged app module directory from to to
+// within appSourceStagePath.
+func renameModuleDir(appSourceStagePath, from, to string) error {
+	if to == "." || to == ".." || strings.ContainsAny(to, `/\`) {
+		return fmt.Errorf("invalid module directory name '%s': must be a single directory name", to)
+	}
+	if from == to {
+		return nil
+	}
+	src := filepath.Join(appSourceStagePath, from)
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return fmt.Errorf("app module directory '%s' not found in staged app source", from)
+	}
+	dst := filepath.Join(appSourceStagePath, to)
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("cannot rename module directory '%s' to '%s': '%s' already exists in the app source", from, to, to)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("failed to rename module directory '%s' to '%s': %w", from, to, err)
+	}
+	return nil
+}
+
+// writeStagingZip zips the contents of stagingDir into w.
+func writeStagingZip(stagingDir string, w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+
+	err := filepath.WalkDir(stagingDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -189,16 +371,20 @@ func CreateFPMArchive(appSourcePath string, outputPath string, meta *metadata.Ap
 			return err
 		}
 
-		fileToZip, err := os.Open(path)
+		info, err := d.Info()
 		if err != nil {
-			return err
+		    return err
 		}
-		defer fileToZip.Close()
 
-		info, err := d.Info()
+		if d.Type()&fs.ModeSymlink != 0 {
+			return addSymlinkToZip(zipWriter, path, zipPath, info)
+		}
+
+		fileToZip, err := os.Open(path)
 		if err != nil {
-		    return err
+			return err
 		}
+		defer fileToZip.Close()
 
 		header, err := zip.FileInfoHeader(info)
 		if err != nil {
@@ -214,14 +400,11 @@ func CreateFPMArchive(appSourcePath string, outputPath string, meta *metadata.Ap
 		_, err = io.Copy(writer, fileToZip)
 		return err
 	})
-
 	if err != nil {
-		// Attempt to remove partially created archive on error
-		os.Remove(outputFilePath)
-		return fmt.Errorf("failed to create zip archive: %w", err)
+		zipWriter.Close()
+		return err
 	}
-
-	return nil
+	return zipWriter.Close()
 }
 
 // copyFile copies a single file from src to dst
@@ -250,12 +433,69 @@ func copyFile(src, dst string) error {
 	    return err
 	}
 	// Set standard permissions for staged files
-	return os.Chmod(dst, 0644)
+	if err := os.Chmod(dst, 0644); err != nil {
+		return err
+	}
+	// Keep the source modification time so checksum cache entries stay valid
+	return os.Chtimes(dst, sourceFileStat.ModTime(), sourceFileStat.ModTime())
+}
+
+// copySymlink recreates the symlink at src as dst. Only links that resolve
+// to a location inside rootDir are allowed, and they are always written
+// with a relative target so they stay valid once the package is extracted.
+func copySymlink(src, dst, rootDir string) error {
+	linkTarget, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	resolved := linkTarget
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(filepath.Dir(src), resolved)
+	}
+	if !isWithinDir(rootDir, resolved) {
+		return fmt.Errorf("symlink %s points outside the app source (%s); replace it with a regular file or a link inside the app", src, linkTarget)
+	}
+	relTarget, err := filepath.Rel(filepath.Dir(src), resolved)
+	if err != nil {
+		return fmt.Errorf("failed to make symlink target of %s relative: %w", src, err)
+	}
+	return os.Symlink(relTarget, dst)
+}
+
+// addSymlinkToZip writes the symlink at path to the archive as a symlink
+// entry whose content is the link target.
+func addSymlinkToZip(zipWriter *zip.Writer, path, zipPath string, info fs.FileInfo) error {
+	linkTarget, err := os.Readlink(path)
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = zipPath
+	header.Method = zip.Store
+	writer, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer, filepath.ToSlash(linkTarget))
+	return err
+}
+
+// isWithinDir reports whether path is dir itself or lies beneath it.
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
 }
 
 // copyDir recursively copies a directory from src to dst, respecting ignore rules
-// ignorer and ignoreRootPath are used for .fpmignore checks
-func copyDir(srcDir, dstDir string, ignorer *ignore.GitIgnore, ignoreRootPath string) error { // Changed gitignore to ignore
+// ignorer and ignoreRootPath are used for .fpmignore checks; record, if not nil,
+// receives the inclusion decision for each file and ignored directory
+func copyDir(srcDir, dstDir string, ignorer *ignoreTree, ignoreRootPath string, record func(FileDecision)) error {
     return filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
         if err != nil {
             return err
@@ -275,22 +515,55 @@ func copyDir(srcDir, dstDir string, ignorer *ignore.GitIgnore, ignoreRootPath st
 
 
         if relPathFromSrcRoot == "." { // Skip the root itself for processing, but ensure dstDir is created
+             if ignorer != nil && pathRelativeToIgnoreRoot != "" {
+                 if err := ignorer.loadDir(pathRelativeToIgnoreRoot); err != nil {
+                     return err
+                 }
+             }
              return os.MkdirAll(dstDir, 0755)
         }
 
-        // Check against ignorer if pathRelativeToIgnoreRoot is valid
-        if ignorer != nil && pathRelativeToIgnoreRoot != "" && ignorer.MatchesPath(pathRelativeToIgnoreRoot) {
-            if d.IsDir() {
-                return filepath.SkipDir
+        // Nested .fpmignore files are applied, not packaged
+        if !d.IsDir() && d.Name() == ignoreFileName {
+            if record != nil {
+                record(FileDecision{Path: filepath.ToSlash(pathRelativeToIgnoreRoot), Source: ExcludedByRootSkip})
             }
             return nil
         }
 
+        // Check against ignorer if pathRelativeToIgnoreRoot is valid
+        if ignorer != nil && pathRelativeToIgnoreRoot != "" {
+            if ignored, decision := ignorer.match(pathRelativeToIgnoreRoot); ignored {
+                decision.Path = filepath.ToSlash(pathRelativeToIgnoreRoot)
+                if d.IsDir() {
+                    decision.Path += "/"
+                }
+                if record != nil {
+                    record(decision)
+                }
+                if d.IsDir() {
+                    return filepath.SkipDir
+                }
+                return nil
+            }
+        }
+
         targetPath := filepath.Join(dstDir, relPathFromSrcRoot)
 
         if d.IsDir() {
+            if ignorer != nil && pathRelativeToIgnoreRoot != "" {
+                if err := ignorer.loadDir(pathRelativeToIgnoreRoot); err != nil {
+                    return err
+                }
+            }
             return os.MkdirAll(targetPath, 0755) // Use fixed permissions for staging directories
         }
+        if record != nil {
+            record(FileDecision{Path: filepath.ToSlash(pathRelativeToIgnoreRoot), Included: true})
+        }
+        if d.Type()&fs.ModeSymlink != 0 {
+            return copySymlink(path, targetPath, srcDir)
+        }
         return copyFile(path, targetPath) // copyFile will handle file permissions
     })
 }
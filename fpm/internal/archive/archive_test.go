@@ -178,3 +178,581 @@ func TestCreateFPMArchive(t *testing.T) {
         }
     }
 }
+
+func TestCreateFPMArchiveWithStagingDir(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-archive-staging-")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	appName := "staged_app"
+	appVersion := "1.0.0"
+	mockAppBasePath := filepath.Join(tmpDir, "apps")
+	outputPath := filepath.Join(tmpDir, "output")
+	stagingParent := filepath.Join(tmpDir, "staging") // Does not exist yet
+	appSourcePath := filepath.Join(mockAppBasePath, appName)
+
+	createMockApp(t, mockAppBasePath, appName, map[string]string{
+		"staged_app/hooks.py": "app_name = 'staged_app'",
+	}, "")
+
+	meta, err := metadata.GenerateAppMetadata(appSourcePath, appVersion)
+	if err != nil {
+		t.Fatalf("Failed to generate metadata: %v", err)
+	}
+
+	opts := ArchiveOptions{StagingDir: stagingParent, KeepStaging: true}
+	result, err := CreateFPMArchiveWithOptions(appSourcePath, outputPath, meta, appVersion, opts)
+	if err != nil {
+		t.Fatalf("CreateFPMArchiveWithOptions failed: %v", err)
+	}
+
+	if filepath.Dir(result.StagingPath) != stagingParent {
+		t.Errorf("Expected staging directory under %s, got %s", stagingParent, result.StagingPath)
+	}
+	if _, err := os.Stat(filepath.Join(result.StagingPath, "app_source", appName, "hooks.py")); err != nil {
+		t.Errorf("Expected kept staging directory to contain staged sources: %v", err)
+	}
+	if result.ArchivePath != filepath.Join(outputPath, appName+"-"+appVersion+".fpm") {
+		t.Errorf("Unexpected archive path: %s", result.ArchivePath)
+	}
+
+	// Without KeepStaging the staging directory must be cleaned up
+	opts.KeepStaging = false
+	result, err = CreateFPMArchiveWithOptions(appSourcePath, outputPath, meta, appVersion, opts)
+	if err != nil {
+		t.Fatalf("CreateFPMArchiveWithOptions failed: %v", err)
+	}
+	if _, err := os.Stat(result.StagingPath); !os.IsNotExist(err) {
+		t.Errorf("Expected staging directory %s to be removed, got err %v", result.StagingPath, err)
+	}
+}
+
+func TestCreateFPMArchiveFileDecisions(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-archive-decisions-")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	appName := "decided_app"
+	appVersion := "1.0.0"
+	mockAppBasePath := filepath.Join(tmpDir, "apps")
+	appSourcePath := filepath.Join(mockAppBasePath, appName)
+
+	createMockApp(t, mockAppBasePath, appName, map[string]string{
+		"decided_app/hooks.py":       "app_name = 'decided_app'",
+		"decided_app/hooks.pyc":      "compiled",
+		"decided_app/notes.txt":      "internal notes",
+		"app_metadata.json":          `{"packageName": "decided_app"}`,
+		"requirements.txt":           "frappe",
+		"decided_app/keep/module.py": "pass",
+	}, "")
+
+	meta, err := metadata.LoadAppMetadata(appSourcePath)
+	if err != nil {
+		t.Fatalf("Failed to load mock app metadata: %v", err)
+	}
+
+	t.Run("default ignore patterns", func(t *testing.T) {
+		result, err := CreateFPMArchiveWithOptions(appSourcePath, filepath.Join(tmpDir, "out-default"), meta, appVersion, ArchiveOptions{})
+		if err != nil {
+			t.Fatalf("CreateFPMArchiveWithOptions failed: %v", err)
+		}
+		decisions := make(map[string]FileDecision)
+		for _, d := range result.FileDecisions {
+			decisions[d.Path] = d
+		}
+
+		if d, ok := decisions["decided_app/hooks.py"]; !ok || !d.Included {
+			t.Errorf("Expected decided_app/hooks.py to be reported as included, got %+v (found: %v)", d, ok)
+		}
+		if d, ok := decisions["requirements.txt"]; !ok || !d.Included {
+			t.Errorf("Expected requirements.txt to be reported as included, got %+v (found: %v)", d, ok)
+		}
+		d, ok := decisions["decided_app/hooks.pyc"]
+		if !ok || d.Included || d.Source != ExcludedByDefaultIgnore || d.Pattern != "*.pyc" {
+			t.Errorf("Expected decided_app/hooks.pyc excluded by default pattern '*.pyc', got %+v (found: %v)", d, ok)
+		}
+		if !strings.Contains(d.String(), "default ignore pattern '*.pyc'") {
+			t.Errorf("Unexpected decision string: %s", d.String())
+		}
+		if d, ok := decisions["app_metadata.json"]; !ok || d.Included || d.Source != ExcludedByRootSkip {
+			t.Errorf("Expected app_metadata.json excluded by root skip, got %+v (found: %v)", d, ok)
+		}
+	})
+
+	t.Run("fpmignore patterns", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(appSourcePath, ".fpmignore"), []byte("# comment\n*.txt\n"), 0644); err != nil {
+			t.Fatalf("Failed to write .fpmignore: %v", err)
+		}
+		defer os.Remove(filepath.Join(appSourcePath, ".fpmignore"))
+
+		result, err := CreateFPMArchiveWithOptions(appSourcePath, filepath.Join(tmpDir, "out-fpmignore"), meta, appVersion, ArchiveOptions{})
+		if err != nil {
+			t.Fatalf("CreateFPMArchiveWithOptions failed: %v", err)
+		}
+		var found bool
+		for _, d := range result.FileDecisions {
+			if d.Path != "decided_app/notes.txt" {
+				continue
+			}
+			found = true
+			if d.Included || d.Source != ExcludedByFpmignore || d.LineNo != 2 || d.Pattern != "*.txt" {
+				t.Errorf("Expected notes.txt excluded by .fpmignore line 2, got %+v", d)
+			}
+		}
+		if !found {
+			t.Errorf("Expected a decision for decided_app/notes.txt")
+		}
+	})
+}
+
+func TestCreateFPMArchiveSymlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	appName := "linked_app"
+	appVersion := "1.0.0"
+	mockAppBasePath := filepath.Join(tmpDir, "apps")
+	appSourcePath := filepath.Join(mockAppBasePath, appName)
+
+	createMockApp(t, mockAppBasePath, appName, map[string]string{
+		"linked_app/hooks.py":          "app_name = 'linked_app'",
+		"linked_app/public/js/real.js": "console.log('real');",
+	}, "")
+	if err := os.Symlink("real.js", filepath.Join(appSourcePath, "linked_app", "public", "js", "alias.js")); err != nil {
+		t.Fatalf("Failed to create file symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join("..", "linked_app", "public"), filepath.Join(appSourcePath, "linked_app", "assets")); err != nil {
+		t.Fatalf("Failed to create dir symlink: %v", err)
+	}
+
+	meta, err := metadata.GenerateAppMetadata(appSourcePath, appVersion)
+	if err != nil {
+		t.Fatalf("Failed to generate metadata: %v", err)
+	}
+	result, err := CreateFPMArchiveWithOptions(appSourcePath, filepath.Join(tmpDir, "output"), meta, appVersion, ArchiveOptions{})
+	if err != nil {
+		t.Fatalf("CreateFPMArchiveWithOptions failed with internal symlinks: %v", err)
+	}
+
+	extractDir := filepath.Join(tmpDir, "extracted")
+	if err := ExtractFPMArchive(result.ArchivePath, extractDir); err != nil {
+		t.Fatalf("ExtractFPMArchive failed: %v", err)
+	}
+
+	aliasPath := filepath.Join(extractDir, "app_source", "linked_app", "public", "js", "alias.js")
+	target, err := os.Readlink(aliasPath)
+	if err != nil {
+		t.Fatalf("Expected alias.js to be extracted as a symlink: %v", err)
+	}
+	if target != "real.js" {
+		t.Errorf("Symlink target mismatch. Got '%s', want '%s'", target, "real.js")
+	}
+	content, err := os.ReadFile(aliasPath)
+	if err != nil || string(content) != "console.log('real');" {
+		t.Errorf("Expected symlink to resolve to real.js content, got '%s' (err: %v)", string(content), err)
+	}
+	if _, err := os.Stat(filepath.Join(extractDir, "app_source", "linked_app", "assets", "js", "real.js")); err != nil {
+		t.Errorf("Expected directory symlink to resolve after extraction: %v", err)
+	}
+
+	t.Run("symlink outside source is rejected", func(t *testing.T) {
+		outsideFile := filepath.Join(tmpDir, "outside.txt")
+		if err := os.WriteFile(outsideFile, []byte("secret"), 0644); err != nil {
+			t.Fatalf("Failed to write outside file: %v", err)
+		}
+		escapeLink := filepath.Join(appSourcePath, "linked_app", "escape.txt")
+		if err := os.Symlink(outsideFile, escapeLink); err != nil {
+			t.Fatalf("Failed to create escaping symlink: %v", err)
+		}
+		defer os.Remove(escapeLink)
+
+		_, err := CreateFPMArchiveWithOptions(appSourcePath, filepath.Join(tmpDir, "output-escape"), meta, appVersion, ArchiveOptions{})
+		if err == nil || !strings.Contains(err.Error(), "points outside the app source") {
+			t.Errorf("Expected error for symlink pointing outside the source, got %v", err)
+		}
+	})
+}
+
+// writeSymlinkEntry adds a symlink entry named name pointing at target.
+func writeSymlinkEntry(w *zip.Writer, name string, target string) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Store}
+	header.SetMode(os.ModeSymlink | 0777)
+	f, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write([]byte(target))
+	return err
+}
+
+func TestExtractFPMArchiveRejectsEscapes(t *testing.T) {
+	testCases := []struct {
+		name    string
+		write   func(w *zip.Writer) error
+		errPart string
+	}{
+		{
+			name: "path traversal",
+			write: func(w *zip.Writer) error {
+				f, err := w.Create("../evil.txt")
+				if err != nil {
+					return err
+				}
+				_, err = f.Write([]byte("evil"))
+				return err
+			},
+			errPart: "outside",
+		},
+		{
+			name: "escaping symlink",
+			write: func(w *zip.Writer) error {
+				return writeSymlinkEntry(w, "app_source/link", "../../etc/passwd")
+			},
+			errPart: "points outside the package",
+		},
+		{
+			// Each entry is inside destDir lexically, but l2 is created
+			// through l1 and so lands in the parent of destDir
+			name: "chained symlinks",
+			write: func(w *zip.Writer) error {
+				if err := writeSymlinkEntry(w, "l1", "."); err != nil {
+					return err
+				}
+				if err := writeSymlinkEntry(w, "l1/l2", ".."); err != nil {
+					return err
+				}
+				f, err := w.Create("l2/pwned.txt")
+				if err != nil {
+					return err
+				}
+				_, err = f.Write([]byte("pwned"))
+				return err
+			},
+			errPart: "through a symlink",
+		},
+		{
+			name: "symlink target through an extracted symlink",
+			write: func(w *zip.Writer) error {
+				if err := writeSymlinkEntry(w, "l1", "."); err != nil {
+					return err
+				}
+				return writeSymlinkEntry(w, "l2", "l1/..")
+			},
+			errPart: "points outside the package",
+		},
+		{
+			name: "file written over an extracted symlink",
+			write: func(w *zip.Writer) error {
+				if err := writeSymlinkEntry(w, "link", "app_source"); err != nil {
+					return err
+				}
+				f, err := w.Create("link")
+				if err != nil {
+					return err
+				}
+				_, err = f.Write([]byte("evil"))
+				return err
+			},
+			errPart: "through a symlink",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			fpmPath := filepath.Join(tmpDir, "bad.fpm")
+			out, err := os.Create(fpmPath)
+			if err != nil {
+				t.Fatalf("Failed to create archive: %v", err)
+			}
+			zw := zip.NewWriter(out)
+			if err := tc.write(zw); err != nil {
+				t.Fatalf("Failed to write archive entry: %v", err)
+			}
+			zw.Close()
+			out.Close()
+
+			err = ExtractFPMArchive(fpmPath, filepath.Join(tmpDir, "dest"))
+			if err == nil || !strings.Contains(err.Error(), tc.errPart) {
+				t.Errorf("Expected error containing '%s', got %v", tc.errPart, err)
+			}
+			if _, err := os.Stat(filepath.Join(tmpDir, "pwned.txt")); !os.IsNotExist(err) {
+				t.Errorf("Expected nothing to be written outside the extraction directory")
+			}
+		})
+	}
+}
+
+func TestCreateFPMArchiveStripSources(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	appName := "closed_app"
+	appVersion := "1.0.0"
+	mockAppBasePath := filepath.Join(tmpDir, "apps")
+	appSourcePath := filepath.Join(mockAppBasePath, appName)
+
+	createMockApp(t, mockAppBasePath, appName, map[string]string{
+		"closed_app/__init__.py":                    "__version__ = '1.0.0'",
+		"closed_app/hooks.py":                       "app_name = 'closed_app'",
+		"closed_app/modules.txt":                    "Closed App",
+		"closed_app/api.py":                         "def secret(): pass",
+		"closed_app/public/js/widget.js":            "console.log('widget');",
+		"closed_app/doctype/thing/__init__.py":      "",
+		"closed_app/doctype/thing/thing.py":         "class Thing: pass",
+		"closed_app/doctype/thing/thing.json":       "{}",
+		"install_hooks.py":                          "print('hook')",
+		"compiled_assets/js/closed_app.bundle.js":   "minified();",
+		"compiled_assets/css/closed_app.bundle.css": "body{}",
+	}, "")
+
+	meta, err := metadata.GenerateAppMetadata(appSourcePath, appVersion)
+	if err != nil {
+		t.Fatalf("Failed to generate metadata: %v", err)
+	}
+	result, err := CreateFPMArchiveWithOptions(appSourcePath, filepath.Join(tmpDir, "output"), meta, appVersion, ArchiveOptions{StripSources: true})
+	if err != nil {
+		t.Fatalf("CreateFPMArchiveWithOptions failed: %v", err)
+	}
+
+	checkZipContent(t, result.ArchivePath, map[string]*string{
+		"app_metadata.json":                               nil,
+		"install_hooks.py":                                nil,
+		"app_source/closed_app/__init__.py":               nil,
+		"app_source/closed_app/hooks.py":                  nil,
+		"app_source/closed_app/modules.txt":               nil,
+		"app_source/closed_app/doctype/thing/__init__.py": nil,
+		"app_source/closed_app/doctype/thing/thing.json":  nil,
+		"compiled_assets/js/closed_app.bundle.js":         nil,
+		"compiled_assets/css/closed_app.bundle.css":       nil,
+	})
+
+	r, err := zip.OpenReader(result.ArchivePath)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		switch f.Name {
+		case "app_source/closed_app/api.py", "app_source/closed_app/public/js/widget.js", "app_source/closed_app/doctype/thing/thing.py":
+			t.Errorf("Expected raw source %s to be stripped from the package", f.Name)
+		}
+	}
+
+	var strippedDecision bool
+	for _, d := range result.FileDecisions {
+		if d.Path == "closed_app/api.py" {
+			strippedDecision = !d.Included && d.Source == ExcludedByStripSources
+		}
+	}
+	if !strippedDecision {
+		t.Errorf("Expected closed_app/api.py to be reported as excluded by strip sources")
+	}
+}
+
+func TestCreateFPMArchiveNestedFpmignore(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	appName := "nested_app"
+	appVersion := "1.0.0"
+	mockAppBasePath := filepath.Join(tmpDir, "apps")
+	appSourcePath := filepath.Join(mockAppBasePath, appName)
+
+	createMockApp(t, mockAppBasePath, appName, map[string]string{
+		"nested_app/hooks.py":                 "app_name = 'nested_app'",
+		"nested_app/fixtures/.fpmignore":      "*.csv\n!keep.csv\n",
+		"nested_app/fixtures/big.csv":         "a,b",
+		"nested_app/fixtures/keep.csv":        "c,d",
+		"nested_app/fixtures/deep/more.csv":   "e,f",
+		"nested_app/data/big.csv":             "g,h",
+		"nested_app/fixtures/deep/.fpmignore": "!more.csv\n",
+		"nested_app/fixtures/deep/readme.txt": "notes",
+	}, "*.log\n")
+
+	meta, err := metadata.GenerateAppMetadata(appSourcePath, appVersion)
+	if err != nil {
+		t.Fatalf("Failed to generate metadata: %v", err)
+	}
+	result, err := CreateFPMArchiveWithOptions(appSourcePath, filepath.Join(tmpDir, "output"), meta, appVersion, ArchiveOptions{})
+	if err != nil {
+		t.Fatalf("CreateFPMArchiveWithOptions failed: %v", err)
+	}
+
+	checkZipContent(t, result.ArchivePath, map[string]*string{
+		"app_source/nested_app/hooks.py":               nil,
+		"app_source/nested_app/fixtures/keep.csv":      nil,
+		"app_source/nested_app/fixtures/deep/more.csv": nil,
+		"app_source/nested_app/data/big.csv":           nil,
+	})
+
+	r, err := zip.OpenReader(result.ArchivePath)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name == "app_source/nested_app/fixtures/big.csv" || strings.HasSuffix(f.Name, "/.fpmignore") {
+			t.Errorf("Expected %s to be excluded from the package", f.Name)
+		}
+	}
+
+	decisions := make(map[string]FileDecision)
+	for _, d := range result.FileDecisions {
+		decisions[d.Path] = d
+	}
+	d, ok := decisions["nested_app/fixtures/big.csv"]
+	if !ok || d.Included || d.IgnoreFile != "nested_app/fixtures/.fpmignore" || d.LineNo != 1 {
+		t.Errorf("Expected nested_app/fixtures/big.csv excluded by nested_app/fixtures/.fpmignore line 1, got %+v (found: %v)", d, ok)
+	}
+	if !strings.Contains(d.String(), "nested_app/fixtures/.fpmignore line 1: '*.csv'") {
+		t.Errorf("Unexpected decision string: %s", d.String())
+	}
+}
+
+func TestCreateFPMArchiveLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	appName := "bloated_app"
+	appVersion := "1.0.0"
+	mockAppBasePath := filepath.Join(tmpDir, "apps")
+	appSourcePath := filepath.Join(mockAppBasePath, appName)
+
+	createMockApp(t, mockAppBasePath, appName, map[string]string{
+		"bloated_app/hooks.py":                     "app_name = 'bloated_app'",
+		"bloated_app/public/node_modules/big.js":   strings.Repeat("x", 4096),
+		"bloated_app/public/node_modules/small.js": "y",
+	}, "")
+
+	meta, err := metadata.GenerateAppMetadata(appSourcePath, appVersion)
+	if err != nil {
+		t.Fatalf("Failed to generate metadata: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		opts    ArchiveOptions
+		wantErr string
+	}{
+		{"too many files", ArchiveOptions{MaxFiles: 2}, "files, more than the limit of 2"},
+		{"too large", ArchiveOptions{MaxTotalSize: 1024}, "more than the limit of 1024 bytes"},
+		{"within limits", ArchiveOptions{MaxFiles: 100, MaxTotalSize: 1 << 20}, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := CreateFPMArchiveWithOptions(appSourcePath, filepath.Join(t.TempDir(), "output"), meta, appVersion, tc.opts)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CreateFPMArchiveWithOptions failed: %v", err)
+				}
+				if _, err := os.Stat(result.ArchivePath); err != nil {
+					t.Errorf("Expected archive to be created: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Expected error containing '%s', got %v", tc.wantErr, err)
+			}
+			if !strings.Contains(err.Error(), "largest files: app_source/bloated_app/public/node_modules/big.js (4096 bytes)") {
+				t.Errorf("Expected the largest file to be listed first, got %v", err)
+			}
+		})
+	}
+}
+
+func TestContentChecksumExclusions(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	appName := "checked_app"
+	appVersion := "1.0.0"
+	mockAppBasePath := filepath.Join(tmpDir, "apps")
+	appSourcePath := filepath.Join(mockAppBasePath, appName)
+	createMockApp(t, mockAppBasePath, appName, map[string]string{
+		"checked_app/hooks.py": "app_name = 'checked_app'",
+	}, "")
+
+	meta, err := metadata.GenerateAppMetadata(appSourcePath, appVersion)
+	if err != nil {
+		t.Fatalf("Failed to generate metadata: %v", err)
+	}
+	result, err := CreateFPMArchiveWithOptions(appSourcePath, filepath.Join(tmpDir, "output"), meta, appVersion, ArchiveOptions{})
+	if err != nil {
+		t.Fatalf("CreateFPMArchiveWithOptions failed: %v", err)
+	}
+
+	// Verification of the unpacked package uses the same exclusions as packaging
+	extractDir := filepath.Join(tmpDir, "extracted")
+	if err := ExtractFPMArchive(result.ArchivePath, extractDir); err != nil {
+		t.Fatalf("ExtractFPMArchive failed: %v", err)
+	}
+	checksum, err := ContentChecksum(extractDir)
+	if err != nil {
+		t.Fatalf("ContentChecksum failed: %v", err)
+	}
+	if checksum != result.ContentChecksum {
+		t.Fatalf("Expected checksum of unpacked package %s to match recorded %s", checksum, result.ContentChecksum)
+	}
+
+	for _, name := range ChecksumExcludedFiles() {
+		if err := os.WriteFile(filepath.Join(extractDir, name), []byte(`{"added": true}`), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if sum, err := ContentChecksum(extractDir); err != nil || sum != result.ContentChecksum {
+			t.Errorf("Expected adding %s not to change the checksum, got %s (%v)", name, sum, err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(extractDir, "extra.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write extra.json: %v", err)
+	}
+	if sum, _ := ContentChecksum(extractDir); sum == result.ContentChecksum {
+		t.Errorf("Expected a file outside the exclusion list to change the checksum")
+	}
+}
+
+func TestCreateFPMArchiveFpmignoreKeepsDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	appName := "merged_app"
+	appVersion := "1.0.0"
+	mockAppBasePath := filepath.Join(tmpDir, "apps")
+	appSourcePath := filepath.Join(mockAppBasePath, appName)
+	createMockApp(t, mockAppBasePath, appName, map[string]string{
+		"merged_app/hooks.py":              "app_name = 'merged_app'",
+		"merged_app/hooks.pyc":             "compiled",
+		"merged_app/__pycache__/hooks.pyc": "compiled",
+		"merged_app/README.md":             "docs",
+		"merged_app/debug.log":             "log",
+		"merged_app/fixtures/keep.log":     "fixture log",
+	}, "*.md\n!merged_app/fixtures/*.log\n")
+
+	meta, err := metadata.GenerateAppMetadata(appSourcePath, appVersion)
+	if err != nil {
+		t.Fatalf("Failed to generate metadata: %v", err)
+	}
+	result, err := CreateFPMArchiveWithOptions(appSourcePath, filepath.Join(tmpDir, "output"), meta, appVersion, ArchiveOptions{})
+	if err != nil {
+		t.Fatalf("CreateFPMArchiveWithOptions failed: %v", err)
+	}
+	decisions := make(map[string]FileDecision)
+	for _, d := range result.FileDecisions {
+		decisions[d.Path] = d
+	}
+
+	if d := decisions["merged_app/hooks.pyc"]; d.Included || d.Source != ExcludedByDefaultIgnore || d.Pattern != "*.pyc" {
+		t.Errorf("Expected *.pyc to stay excluded by the defaults alongside a .fpmignore, got %+v", d)
+	}
+	if d := decisions["merged_app/__pycache__/hooks.pyc"]; d.Included || d.Source != ExcludedByDefaultIgnore {
+		t.Errorf("Expected __pycache__/ contents to stay excluded by the defaults, got %+v", d)
+	}
+	if d := decisions["merged_app/README.md"]; d.Included || d.Source != ExcludedByFpmignore || d.LineNo != 1 {
+		t.Errorf("Expected README.md excluded by .fpmignore line 1, got %+v", d)
+	}
+	if d := decisions["merged_app/debug.log"]; d.Included || d.Source != ExcludedByDefaultIgnore {
+		t.Errorf("Expected debug.log excluded by the defaults, got %+v", d)
+	}
+	if d := decisions["merged_app/fixtures/keep.log"]; !d.Included {
+		t.Errorf("Expected a '!' pattern in .fpmignore to re-include a default-ignored file, got %+v", d)
+	}
+}
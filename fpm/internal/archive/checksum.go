@@ -0,0 +1,28 @@
+package archive
+
+import (
+	"fpm/internal/metadata"
+	"fpm/internal/utils"
+)
+
+// ChecksumExcludedFiles returns the paths, relative to the package root,
+// that the content checksum leaves out: the metadata file (see
+// metadata.FileName), which records the checksum, and files describing the
+// package that are written alongside its contents. Packaging and
+// verification both go through this list so they cannot disagree about
+// what the checksum covers.
+func ChecksumExcludedFiles() []string {
+	return []string{metadata.FileName, "_manifest.json", "_source.json"}
+}
+
+// ContentChecksum returns the content checksum of the unpacked package in
+// packageDir, as recorded in its metadata file when it was built.
+func ContentChecksum(packageDir string) (string, error) {
+	return checksumPackageDir(packageDir, nil)
+}
+
+// checksumPackageDir computes the content checksum of packageDir, reusing
+// per-file hashes from cache when it is not nil.
+func checksumPackageDir(packageDir string, cache *utils.ChecksumCache) (string, error) {
+	return utils.CalculateDirectoryChecksumCached(packageDir, cache, ChecksumExcludedFiles()...)
+}
@@ -0,0 +1,199 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fpm/internal/metadata"
+)
+
+// ReadPackageMetadata returns the metadata file (see metadata.FileName) of the .fpm package at
+// fpmPath, read directly from the archive without extracting it.
+func ReadPackageMetadata(fpmPath string) (*metadata.AppMetadata, error) {
+	reader, err := zip.OpenReader(fpmPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package %s: %w", fpmPath, err)
+	}
+	defer reader.Close()
+
+	for _, f := range reader.File {
+		if f.Name != metadata.FileName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in %s: %w", metadata.FileName, fpmPath, err)
+		}
+		defer rc.Close()
+		meta := &metadata.AppMetadata{}
+		if err := json.NewDecoder(rc).Decode(meta); err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %s: %w", metadata.FileName, fpmPath, err)
+		}
+		return meta, nil
+	}
+	return nil, fmt.Errorf("package %s has no %s", fpmPath, metadata.FileName)
+}
+
+// ExtractFPMArchive extracts the .fpm package at fpmPath into destDir.
+// Entries that would be written outside destDir or through a symlink
+// extracted earlier are rejected, as are symlink entries whose target
+// resolves outside destDir.
+func ExtractFPMArchive(fpmPath string, destDir string) error {
+	reader, err := zip.OpenReader(fpmPath)
+	if err != nil {
+		return fmt.Errorf("failed to open package %s: %w", fpmPath, err)
+	}
+	defer reader.Close()
+
+	absDestDir, err := filepath.Abs(destDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for %s: %w", destDir, err)
+	}
+	if err := os.MkdirAll(absDestDir, 0755); err != nil {
+		return fmt.Errorf("failed to create extraction directory %s: %w", absDestDir, err)
+	}
+
+	for _, f := range reader.File {
+		if err := extractZipEntry(f, absDestDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipEntry writes a single archive entry beneath destDir.
+func extractZipEntry(f *zip.File, destDir string) error {
+	targetPath := filepath.Join(destDir, filepath.FromSlash(f.Name))
+	if !isWithinDir(destDir, targetPath) {
+		return fmt.Errorf("package entry %s would be extracted outside %s", f.Name, destDir)
+	}
+	if err := checkNoSymlinkInPath(destDir, targetPath); err != nil {
+		return fmt.Errorf("package entry %s would be extracted through a symlink: %w", f.Name, err)
+	}
+
+	if f.FileInfo().IsDir() || strings.HasSuffix(f.Name, "/") {
+		return os.MkdirAll(targetPath, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open package entry %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	if f.Mode()&os.ModeSymlink != 0 {
+		linkBytes, err := io.ReadAll(rc)
+		if err != nil {
+			return fmt.Errorf("failed to read symlink entry %s: %w", f.Name, err)
+		}
+		linkTarget := filepath.FromSlash(string(linkBytes))
+		if _, ok := resolveWithin(destDir, filepath.Dir(targetPath), linkTarget, 0); filepath.IsAbs(linkTarget) || !ok {
+			return fmt.Errorf("symlink entry %s points outside the package (%s)", f.Name, string(linkBytes))
+		}
+		return os.Symlink(linkTarget, targetPath)
+	}
+
+	outFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	if _, err := io.Copy(outFile, rc); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// checkNoSymlinkInPath returns an error if targetPath, or any directory
+// between destDir and it, already exists as a symlink. Paths are only
+// checked lexically before extraction, so writing through a symlink
+// extracted from the same package could otherwise escape destDir.
+func checkNoSymlinkInPath(destDir, targetPath string) error {
+	rel, err := filepath.Rel(destDir, targetPath)
+	if err != nil || rel == "." {
+		return err
+	}
+	current := destDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil // Nothing below a missing directory exists yet
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", current)
+		}
+	}
+	return nil
+}
+
+// maxSymlinkHops bounds how many symlinks resolveWithin follows, so
+// symlink loops in a package are rejected instead of recursing forever.
+const maxSymlinkHops = 40
+
+// resolveWithin resolves linkTarget, a relative symlink target in the
+// directory dir, and reports whether it stays inside destDir. Symlinks
+// already on disk are followed, so a target such as "link/.." is judged by
+// where link really points rather than lexically. A ".." after a component
+// that does not exist yet is rejected, since a later entry could make that
+// component a symlink and move where the ".." leads.
+func resolveWithin(destDir, dir, linkTarget string, hops int) (string, bool) {
+	if hops > maxSymlinkHops {
+		return "", false
+	}
+	current := dir
+	missing := false
+	for _, part := range strings.Split(linkTarget, string(filepath.Separator)) {
+		switch {
+		case part == "" || part == ".":
+			continue
+		case part == "..":
+			if missing {
+				return "", false
+			}
+			current = filepath.Dir(current)
+			if !isWithinDir(destDir, current) {
+				return "", false
+			}
+			continue
+		}
+		next := filepath.Join(current, part)
+		if missing {
+			current = next
+			continue
+		}
+		info, err := os.Lstat(next)
+		switch {
+		case os.IsNotExist(err):
+			missing = true
+			current = next
+		case err != nil:
+			return "", false
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(next)
+			if err != nil || filepath.IsAbs(target) {
+				return "", false
+			}
+			resolved, ok := resolveWithin(destDir, current, target, hops+1)
+			if !ok {
+				return "", false
+			}
+			current = resolved
+		default:
+			current = next
+		}
+	}
+	return current, isWithinDir(destDir, current)
+}
@@ -0,0 +1,79 @@
+package archive
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// limitOffenderCount is the number of largest files listed when a package
+// exceeds its size or file count limit.
+const limitOffenderCount = 5
+
+// stagedFile is a file in the staging directory and its size in bytes.
+type stagedFile struct {
+	path string
+	size int64
+}
+
+// checkStagingLimits returns an error if the staged package holds more than
+// maxFiles files or more than maxTotalSize bytes. A limit of zero or less
+// is not enforced. The error lists the largest staged files, which are
+// usually the ones that were included by accident.
+func checkStagingLimits(stagingDir string, maxFiles int, maxTotalSize int64) error {
+	if maxFiles <= 0 && maxTotalSize <= 0 {
+		return nil
+	}
+
+	var files []stagedFile
+	var totalSize int64
+	err := filepath.WalkDir(stagingDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(stagingDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, stagedFile{path: filepath.ToSlash(relPath), size: info.Size()})
+		totalSize += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to measure staged package: %w", err)
+	}
+
+	var problem string
+	switch {
+	case maxFiles > 0 && len(files) > maxFiles:
+		problem = fmt.Sprintf("package contains %d files, more than the limit of %d", len(files), maxFiles)
+	case maxTotalSize > 0 && totalSize > maxTotalSize:
+		problem = fmt.Sprintf("package contents total %d bytes, more than the limit of %d bytes", totalSize, maxTotalSize)
+	default:
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].size != files[j].size {
+			return files[i].size > files[j].size
+		}
+		return files[i].path < files[j].path
+	})
+	if len(files) > limitOffenderCount {
+		files = files[:limitOffenderCount]
+	}
+	offenders := make([]string, len(files))
+	for i, f := range files {
+		offenders[i] = fmt.Sprintf("%s (%d bytes)", f.path, f.size)
+	}
+	return fmt.Errorf("%s; largest files: %s", problem, strings.Join(offenders, ", "))
+}
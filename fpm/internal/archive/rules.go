@@ -0,0 +1,331 @@
+package archive
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sabhiram/go-gitignore"
+)
+
+// ExclusionSource identifies the kind of rule that excluded a path from a package.
+type ExclusionSource string
+
+const (
+	// ExcludedByDefaultIgnore means a built-in default ignore pattern matched.
+	ExcludedByDefaultIgnore ExclusionSource = "default ignore"
+	// ExcludedByFpmignore means a pattern from the app's .fpmignore matched.
+	ExcludedByFpmignore ExclusionSource = ".fpmignore"
+	// ExcludedByRootSkip means the item is handled by fpm itself (e.g.
+	// app_metadata.json is regenerated and .fpmignore files are applied)
+	// and is never copied verbatim.
+	ExcludedByRootSkip ExclusionSource = "root skip"
+	// ExcludedByStripSources means the file is a raw source file dropped
+	// because the package was built with StripSources.
+	ExcludedByStripSources ExclusionSource = "strip sources"
+	// ExcludedByAssets means compiled_assets was left out because the
+	// package was built with ExcludeCompiledAssets.
+	ExcludedByAssets ExclusionSource = "assets excluded"
+)
+
+// stripSourcesKeepList names the source files that are still packaged with
+// StripSources because Frappe needs them to import and register the app.
+var stripSourcesKeepList = map[string]bool{
+	"__init__.py": true,
+	"hooks.py":    true,
+	"modules.txt": true,
+}
+
+// isStrippedSource reports whether relPath is a raw .py/.js source file
+// that StripSources removes from app_source.
+func isStrippedSource(relPath string) bool {
+	base := filepath.Base(relPath)
+	if stripSourcesKeepList[base] {
+		return false
+	}
+	switch filepath.Ext(base) {
+	case ".py", ".js":
+		return true
+	}
+	return false
+}
+
+// FileDecision records whether a path from the app source was included in
+// the package and, if it was excluded, which rule made that decision.
+type FileDecision struct {
+	// Path is relative to the app source directory, using forward slashes.
+	// Directories that were skipped as a whole end in "/".
+	Path     string
+	Included bool
+	// Source, Pattern and LineNo describe the excluding rule. They are
+	// empty for included paths. LineNo and IgnoreFile are only set for
+	// .fpmignore rules; IgnoreFile is the path of the .fpmignore relative
+	// to the app source directory.
+	Source     ExclusionSource
+	Pattern    string
+	LineNo     int
+	IgnoreFile string
+}
+
+// String formats the decision for verbose output.
+func (d FileDecision) String() string {
+	if d.Included {
+		return fmt.Sprintf("included: %s", d.Path)
+	}
+	switch d.Source {
+	case ExcludedByFpmignore:
+		ignoreFile := d.IgnoreFile
+		if ignoreFile == "" {
+			ignoreFile = ignoreFileName
+		}
+		return fmt.Sprintf("excluded: %s (%s line %d: '%s')", d.Path, ignoreFile, d.LineNo, d.Pattern)
+	case ExcludedByDefaultIgnore:
+		return fmt.Sprintf("excluded: %s (default ignore pattern '%s')", d.Path, d.Pattern)
+	default:
+		return fmt.Sprintf("excluded: %s (%s)", d.Path, d.Source)
+	}
+}
+
+// ignoreFileName is the name of the files holding ignore patterns.
+const ignoreFileName = ".fpmignore"
+
+// ignoreRules wraps a compiled ignore matcher and remembers where its
+// patterns came from so exclusions can be attributed.
+type ignoreRules struct {
+	matcher *ignore.GitIgnore
+	// negations matches the paths named by the "!" patterns alone. The
+	// matcher only reports a negation that cancels an earlier pattern in
+	// the same file, but in a nested file it may re-include a path that a
+	// parent directory's file ignores.
+	negations *ignore.GitIgnore
+	source    ExclusionSource
+	lines     []string
+	// file is the slash-separated path of the .fpmignore the patterns were
+	// read from, relative to the app source directory. Empty for defaults.
+	file string
+}
+
+// newIgnoreRules compiles lines into an ignoreRules attributed to source.
+func newIgnoreRules(source ExclusionSource, lines ...string) *ignoreRules {
+	var negated []string
+	for _, line := range lines {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "!") {
+			negated = append(negated, trimmed[1:])
+		}
+	}
+	return &ignoreRules{
+		matcher:   ignore.CompileIgnoreLines(lines...),
+		negations: ignore.CompileIgnoreLines(negated...),
+		source:    source,
+		lines:     lines,
+	}
+}
+
+// IgnoreRule is one effective ignore pattern and where it came from.
+type IgnoreRule struct {
+	Pattern string
+	Source  ExclusionSource
+	// File and LineNo locate .fpmignore patterns; File is relative to the
+	// app source directory. Both are empty for default patterns.
+	File   string
+	LineNo int
+}
+
+// String formats the rule as "<origin>: <pattern>".
+func (r IgnoreRule) String() string {
+	if r.Source == ExcludedByFpmignore {
+		return fmt.Sprintf("%s line %d: %s", r.File, r.LineNo, r.Pattern)
+	}
+	return fmt.Sprintf("%s: %s", r.Source, r.Pattern)
+}
+
+// patterns returns the rules' non-blank, non-comment patterns in order.
+func (r *ignoreRules) patterns() []IgnoreRule {
+	var rules []IgnoreRule
+	for i, line := range r.lines {
+		pattern := strings.TrimSpace(line)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		rule := IgnoreRule{Pattern: pattern, Source: r.source}
+		if r.source == ExcludedByFpmignore {
+			rule.File, rule.LineNo = r.file, i+1
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// loadRootIgnoreRules returns the rules of the .fpmignore at the root of
+// the app source, or nil if it has none. The default patterns apply either
+// way; see ignoreTree.
+func loadRootIgnoreRules(absAppSourcePath string) (*ignoreRules, error) {
+	ignoreFilePath := filepath.Join(absAppSourcePath, ignoreFileName)
+	if _, err := os.Stat(ignoreFilePath); err != nil {
+		return nil, nil
+	}
+	ignoreBytes, err := os.ReadFile(ignoreFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .fpmignore: %w", err)
+	}
+	rules := newIgnoreRules(ExcludedByFpmignore, strings.Split(string(ignoreBytes), "\n")...)
+	rules.file = ignoreFileName
+	return rules, nil
+}
+
+// EffectiveIgnoreRules returns the ignore patterns packaging would apply to
+// the app source at appSourcePath, from lowest to highest precedence: the
+// defaults, the root .fpmignore and nested .fpmignore files in path order.
+// Defaults repeated in the root .fpmignore are listed once, as part of the
+// file. Nested files inside ignored directories are not listed, as
+// packaging never reads them.
+func EffectiveIgnoreRules(appSourcePath string) ([]IgnoreRule, error) {
+	absAppSourcePath, err := filepath.Abs(appSourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for app source: %w", err)
+	}
+	rootRules, err := loadRootIgnoreRules(absAppSourcePath)
+	if err != nil {
+		return nil, err
+	}
+	tree := newIgnoreTree(absAppSourcePath, rootRules)
+
+	err = filepath.WalkDir(absAppSourcePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == absAppSourcePath {
+			return nil
+		}
+		relPath, err := filepath.Rel(absAppSourcePath, path)
+		if err != nil {
+			return err
+		}
+		if ignored, _ := tree.match(relPath); ignored {
+			return filepath.SkipDir
+		}
+		return tree.loadDir(relPath)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s for .fpmignore files: %w", absAppSourcePath, err)
+	}
+
+	dirs := make([]string, 0, len(tree.rules))
+	for dir := range tree.rules {
+		if dir != "." {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	var rules []IgnoreRule
+	var rootPatterns []IgnoreRule
+	if rootRules != nil {
+		rootPatterns = rootRules.patterns()
+	}
+	repeated := make(map[string]bool, len(rootPatterns))
+	for _, rule := range rootPatterns {
+		repeated[rule.Pattern] = true
+	}
+	for _, rule := range tree.defaults.patterns() {
+		if !repeated[rule.Pattern] {
+			rules = append(rules, rule)
+		}
+	}
+	rules = append(rules, rootPatterns...)
+	for _, dir := range dirs {
+		rules = append(rules, tree.rules[dir].patterns()...)
+	}
+	return rules, nil
+}
+
+// match reports whether relPath is ignored. decided is true when any
+// pattern matched relPath, including a negated one that re-includes it;
+// the returned decision then describes the last matching pattern.
+func (r *ignoreRules) match(relPath string) (ignored bool, decided bool, decision FileDecision) {
+	ignored, pattern := r.matcher.MatchesPathHow(relPath)
+	if pattern == nil {
+		// Only a "!" pattern can still apply, re-including the path
+		return false, r.negations.MatchesPath(relPath), FileDecision{}
+	}
+	decision = FileDecision{Source: r.source, Pattern: strings.TrimSpace(pattern.Line)}
+	if r.source == ExcludedByFpmignore {
+		decision.LineNo = pattern.LineNo
+		decision.IgnoreFile = r.file
+	}
+	return ignored, true, decision
+}
+
+// ignoreTree holds the ignore rules in effect for an app source: the
+// default patterns, the root .fpmignore and any nested .fpmignore files. As
+// with nested .gitignore files, a nested file's patterns are relative to its
+// own directory, and for a given path the deepest file with a matching
+// pattern decides whether it is ignored. The defaults sit below the root
+// .fpmignore, which can re-include a default with a "!" pattern.
+type ignoreTree struct {
+	rootPath string
+	defaults *ignoreRules
+	// rules is keyed by directory, slash-separated and relative to
+	// rootPath, with "." for the root.
+	rules map[string]*ignoreRules
+}
+
+// newIgnoreTree returns an ignoreTree for rootPath using rootRules, which
+// may be nil, at the root.
+func newIgnoreTree(rootPath string, rootRules *ignoreRules) *ignoreTree {
+	tree := &ignoreTree{
+		rootPath: rootPath,
+		defaults: newIgnoreRules(ExcludedByDefaultIgnore, defaultIgnorePatterns...),
+		rules:    make(map[string]*ignoreRules),
+	}
+	if rootRules != nil {
+		tree.rules["."] = rootRules
+	}
+	return tree
+}
+
+// loadDir reads the .fpmignore in relDir, if there is one, so its rules
+// apply to everything beneath relDir. The root's rules are fixed when the
+// tree is created and are not reloaded.
+func (t *ignoreTree) loadDir(relDir string) error {
+	relDir = filepath.ToSlash(relDir)
+	if relDir == "." {
+		return nil
+	}
+	content, err := os.ReadFile(filepath.Join(t.rootPath, filepath.FromSlash(relDir), ignoreFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s/%s: %w", relDir, ignoreFileName, err)
+	}
+	rules := newIgnoreRules(ExcludedByFpmignore, strings.Split(string(content), "\n")...)
+	rules.file = path.Join(relDir, ignoreFileName)
+	t.rules[relDir] = rules
+	return nil
+}
+
+// match reports whether relPath, relative to the tree's root, is ignored.
+// When it is, the returned decision describes the pattern responsible.
+func (t *ignoreTree) match(relPath string) (bool, FileDecision) {
+	relPath = filepath.ToSlash(relPath)
+	for dir := path.Dir(relPath); ; dir = path.Dir(dir) {
+		if rules, ok := t.rules[dir]; ok {
+			pathInDir := relPath
+			if dir != "." {
+				pathInDir = strings.TrimPrefix(relPath, dir+"/")
+			}
+			if ignored, decided, decision := rules.match(pathInDir); decided {
+				return ignored, decision
+			}
+		}
+		if dir == "." {
+			ignored, _, decision := t.defaults.match(relPath)
+			return ignored, decision
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AppsDirName and SitesDirName are the standard directory names inside a bench.
+const (
+	AppsDirName  = "apps"
+	SitesDirName = "sites"
+)
+
+// AppsTxtPath returns the path of the bench's sites/apps.txt.
+func AppsTxtPath(benchPath string) string {
+	return filepath.Join(benchPath, SitesDirName, "apps.txt")
+}
+
+// SiteAppsTxtPath returns the apps.txt to use for site given the bench's
+// sites directory: <sitesDir>/<site>/apps.txt if the site has its own,
+// otherwise the bench-wide <sitesDir>/apps.txt. An empty site always
+// yields the bench-wide file.
+func SiteAppsTxtPath(sitesDir string, site string) string {
+	if site != "" {
+		sitePath := filepath.Join(sitesDir, site, "apps.txt")
+		if info, err := os.Stat(sitePath); err == nil && !info.IsDir() {
+			return sitePath
+		}
+	}
+	return filepath.Join(sitesDir, "apps.txt")
+}
+
+// ReadAppsTxt returns the app names listed in the bench's sites/apps.txt,
+// in file order, skipping blank lines. A missing file yields no apps.
+func ReadAppsTxt(benchPath string) ([]string, error) {
+	return ReadAppsTxtFile(AppsTxtPath(benchPath))
+}
+
+// ReadAppsTxtFile is like ReadAppsTxt but reads the apps.txt at path.
+func ReadAppsTxtFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if app := strings.TrimSpace(line); app != "" {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+// AppendAppsTxt appends the given app names to the bench's sites/apps.txt,
+// creating the file if needed and keeping one app per line.
+func AppendAppsTxt(benchPath string, apps []string) error {
+	if len(apps) == 0 {
+		return nil
+	}
+	appsTxtPath := AppsTxtPath(benchPath)
+	content, err := os.ReadFile(appsTxtPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var builder strings.Builder
+	builder.Write(content)
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		builder.WriteString("\n")
+	}
+	for _, app := range apps {
+		builder.WriteString(app + "\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(appsTxtPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(appsTxtPath, []byte(builder.String()), 0644)
+}
+
+// SyncReport describes where sites/apps.txt and the apps directory disagree.
+type SyncReport struct {
+	// UnlistedSymlinks are apps with a valid symlink in apps/ that are
+	// missing from apps.txt.
+	UnlistedSymlinks []string
+	// MissingApps are apps.txt entries with no app directory or symlink
+	// (or only a broken symlink) in apps/.
+	MissingApps []string
+}
+
+// InSync reports whether no discrepancies were found.
+func (r *SyncReport) InSync() bool {
+	return len(r.UnlistedSymlinks) == 0 && len(r.MissingApps) == 0
+}
+
+// CheckSync compares the bench's sites/apps.txt with the contents of its
+// apps directory.
+func CheckSync(benchPath string) (*SyncReport, error) {
+	listed, err := ReadAppsTxt(benchPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", AppsTxtPath(benchPath), err)
+	}
+	listedSet := make(map[string]bool, len(listed))
+	for _, app := range listed {
+		listedSet[app] = true
+	}
+
+	appsDir := filepath.Join(benchPath, AppsDirName)
+	entries, err := os.ReadDir(appsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apps directory %s: %w", appsDir, err)
+	}
+
+	report := &SyncReport{}
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink == 0 || listedSet[entry.Name()] {
+			continue
+		}
+		// Only symlinks that resolve to a directory are installable apps
+		if info, err := os.Stat(filepath.Join(appsDir, entry.Name())); err == nil && info.IsDir() {
+			report.UnlistedSymlinks = append(report.UnlistedSymlinks, entry.Name())
+		}
+	}
+
+	for _, app := range listed {
+		if info, err := os.Stat(filepath.Join(appsDir, app)); err != nil || !info.IsDir() {
+			report.MissingApps = append(report.MissingApps, app)
+		}
+	}
+
+	sort.Strings(report.UnlistedSymlinks)
+	return report, nil
+}
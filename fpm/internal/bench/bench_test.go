@@ -0,0 +1,123 @@
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// createMockBench creates a bench with the given apps.txt content and app
+// directories. Apps listed in links are created as symlinks into a
+// separate store directory.
+func createMockBench(t *testing.T, appsTxt string, dirs []string, links []string) string {
+	t.Helper()
+	benchPath := filepath.Join(t.TempDir(), "bench")
+	storePath := filepath.Join(t.TempDir(), "store")
+	for _, dir := range []string{filepath.Join(benchPath, AppsDirName), filepath.Join(benchPath, SitesDirName), storePath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(AppsTxtPath(benchPath), []byte(appsTxt), 0644); err != nil {
+		t.Fatalf("Failed to write apps.txt: %v", err)
+	}
+	for _, app := range dirs {
+		if err := os.Mkdir(filepath.Join(benchPath, AppsDirName, app), 0755); err != nil {
+			t.Fatalf("Failed to create app dir %s: %v", app, err)
+		}
+	}
+	for _, app := range links {
+		target := filepath.Join(storePath, app)
+		if err := os.Mkdir(target, 0755); err != nil {
+			t.Fatalf("Failed to create store dir %s: %v", app, err)
+		}
+		if err := os.Symlink(target, filepath.Join(benchPath, AppsDirName, app)); err != nil {
+			t.Fatalf("Failed to create symlink for %s: %v", app, err)
+		}
+	}
+	return benchPath
+}
+
+func TestCheckSync(t *testing.T) {
+	benchPath := createMockBench(t, "frappe\nlinked_listed\nghost_app", []string{"frappe"}, []string{"linked_listed", "linked_unlisted"})
+	// A broken symlink is not a valid app and must not be added to apps.txt
+	if err := os.Symlink(filepath.Join(benchPath, "nowhere"), filepath.Join(benchPath, AppsDirName, "broken_link")); err != nil {
+		t.Fatalf("Failed to create broken symlink: %v", err)
+	}
+
+	report, err := CheckSync(benchPath)
+	if err != nil {
+		t.Fatalf("CheckSync failed: %v", err)
+	}
+	if !reflect.DeepEqual(report.UnlistedSymlinks, []string{"linked_unlisted"}) {
+		t.Errorf("UnlistedSymlinks mismatch. Got %v, want %v", report.UnlistedSymlinks, []string{"linked_unlisted"})
+	}
+	if !reflect.DeepEqual(report.MissingApps, []string{"ghost_app"}) {
+		t.Errorf("MissingApps mismatch. Got %v, want %v", report.MissingApps, []string{"ghost_app"})
+	}
+
+	// Fixing appends the unlisted symlink while preserving existing entries
+	if err := AppendAppsTxt(benchPath, report.UnlistedSymlinks); err != nil {
+		t.Fatalf("AppendAppsTxt failed: %v", err)
+	}
+	apps, err := ReadAppsTxt(benchPath)
+	if err != nil {
+		t.Fatalf("ReadAppsTxt failed: %v", err)
+	}
+	expectedApps := []string{"frappe", "linked_listed", "ghost_app", "linked_unlisted"}
+	if !reflect.DeepEqual(apps, expectedApps) {
+		t.Errorf("apps.txt mismatch after fix. Got %v, want %v", apps, expectedApps)
+	}
+
+	report, err = CheckSync(benchPath)
+	if err != nil {
+		t.Fatalf("CheckSync failed: %v", err)
+	}
+	if len(report.UnlistedSymlinks) != 0 {
+		t.Errorf("Expected no unlisted symlinks after fix, got %v", report.UnlistedSymlinks)
+	}
+}
+
+func TestCheckSyncInSync(t *testing.T) {
+	benchPath := createMockBench(t, "frappe\nmy_app\n", []string{"frappe"}, []string{"my_app"})
+
+	report, err := CheckSync(benchPath)
+	if err != nil {
+		t.Fatalf("CheckSync failed: %v", err)
+	}
+	if !report.InSync() {
+		t.Errorf("Expected bench to be in sync, got %+v", report)
+	}
+}
+
+func TestSiteAppsTxtPath(t *testing.T) {
+	benchPath := createMockBench(t, "frappe\n", nil, nil)
+	siteDir := filepath.Join(benchPath, SitesDirName, "site1.local")
+	if err := os.MkdirAll(siteDir, 0755); err != nil {
+		t.Fatalf("Failed to create site dir: %v", err)
+	}
+
+	sitesDir := filepath.Join(benchPath, SitesDirName)
+	if got := SiteAppsTxtPath(sitesDir, "site1.local"); got != AppsTxtPath(benchPath) {
+		t.Errorf("Expected bench apps.txt for a site without its own, got %s", got)
+	}
+	siteAppsTxt := filepath.Join(siteDir, "apps.txt")
+	if err := os.WriteFile(siteAppsTxt, []byte("frappe\nhrms\n"), 0644); err != nil {
+		t.Fatalf("Failed to write site apps.txt: %v", err)
+	}
+	if got := SiteAppsTxtPath(sitesDir, "site1.local"); got != siteAppsTxt {
+		t.Errorf("Expected site apps.txt %s, got %s", siteAppsTxt, got)
+	}
+	if got := SiteAppsTxtPath(sitesDir, ""); got != AppsTxtPath(benchPath) {
+		t.Errorf("Expected bench apps.txt without a site, got %s", got)
+	}
+
+	apps, err := ReadAppsTxtFile(siteAppsTxt)
+	if err != nil {
+		t.Fatalf("ReadAppsTxtFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(apps, []string{"frappe", "hrms"}) {
+		t.Errorf("Unexpected apps: %v", apps)
+	}
+}
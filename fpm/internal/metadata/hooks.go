@@ -0,0 +1,59 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrAppNameNotDeterminable is returned by GetAppNameFromHooks when hooks.py
+// assigns app_name something other than a plain string literal, such as an
+// f-string, a concatenation or a variable, whose value cannot be known
+// without running Python.
+var ErrAppNameNotDeterminable = errors.New("app_name is not a plain string literal")
+
+var (
+	// hooksAppNameRegex matches a top-level app_name assignment in hooks.py,
+	// optionally annotated, and captures the assigned expression.
+	hooksAppNameRegex = regexp.MustCompile(`^app_name\s*(?::[^=]*)?=\s*(.*)$`)
+	// hooksLiteralRegex matches an expression that is a single plain string
+	// literal, optionally followed by a comment.
+	hooksLiteralRegex = regexp.MustCompile(`^(?:'([^'\\]*)'|"([^"\\]*)")\s*(?:#.*)?$`)
+)
+
+// GetAppNameFromHooks returns the app_name declared in the given hooks.py.
+// Single- or double-quoted literals are accepted with any surrounding
+// whitespace; if app_name is assigned more than once, the last assignment
+// wins, as it would in Python. An empty string is returned if no app_name
+// assignment is found. If the assignment is not a plain string literal,
+// ErrAppNameNotDeterminable is returned rather than a guessed value.
+func GetAppNameFromHooks(hooksPath string) (string, error) {
+	content, err := os.ReadFile(hooksPath)
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		return "", fmt.Errorf("%s does not look like a Python source file", hooksPath)
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")) // UTF-8 byte order mark
+
+	var appName string
+	var exprErr error
+	for _, line := range strings.Split(string(content), "\n") {
+		match := hooksAppNameRegex.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+		expr := strings.TrimSpace(match[1])
+		literal := hooksLiteralRegex.FindStringSubmatch(expr)
+		if literal == nil {
+			appName, exprErr = "", fmt.Errorf("%w: app_name = %s", ErrAppNameNotDeterminable, expr)
+			continue
+		}
+		appName, exprErr = strings.TrimSpace(literal[1]+literal[2]), nil
+	}
+	return appName, exprErr
+}
@@ -0,0 +1,70 @@
+package metadata
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetAppNameFromHooks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"double quoted", "app_name = \"my_app\"\napp_title = \"My App\"\n", "my_app"},
+		{"single quoted", "app_publisher = 'Me'\napp_name='other_app'\n", "other_app"},
+		{"missing", "app_title = \"No Name\"\n", ""},
+		{"whitespace padded", "app_name   =   'padded_app'   # the module name\r\n", "padded_app"},
+		{"byte order mark", "\ufeffapp_name = \"bom_app\"\n", "bom_app"},
+		{"annotated", "app_name: str = \"typed_app\"\n", "typed_app"},
+		{"last assignment wins", "app_name = \"first\"\napp_name = \"second\"\n", "second"},
+		{"indented is not top-level", "if True:\n    app_name = \"nested\"\n", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hooksPath := filepath.Join(t.TempDir(), "hooks.py")
+			if err := os.WriteFile(hooksPath, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write hooks.py: %v", err)
+			}
+
+			appName, err := GetAppNameFromHooks(hooksPath)
+			if err != nil {
+				t.Fatalf("GetAppNameFromHooks failed: %v", err)
+			}
+			if appName != tc.expected {
+				t.Errorf("app_name mismatch. Got '%s', want '%s'", appName, tc.expected)
+			}
+		})
+	}
+
+	for _, content := range []string{
+		"app_name = f\"{prefix}_app\"\n",
+		"app_name = \"my\" + suffix\n",
+		"app_name = APP_NAME\n",
+		"app_name = (\n    \"split_app\"\n)\n",
+	} {
+		hooksPath := filepath.Join(t.TempDir(), "hooks.py")
+		if err := os.WriteFile(hooksPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write hooks.py: %v", err)
+		}
+		appName, err := GetAppNameFromHooks(hooksPath)
+		if !errors.Is(err, ErrAppNameNotDeterminable) || appName != "" {
+			t.Errorf("Expected ErrAppNameNotDeterminable for %q, got '%s', %v", content, appName, err)
+		}
+	}
+
+	binaryPath := filepath.Join(t.TempDir(), "hooks.py")
+	if err := os.WriteFile(binaryPath, []byte("app_name = \"bin\"\x00\x01\x02"), 0644); err != nil {
+		t.Fatalf("Failed to write hooks.py: %v", err)
+	}
+	if _, err := GetAppNameFromHooks(binaryPath); err == nil {
+		t.Errorf("Expected error for binary hooks.py, got nil")
+	}
+
+	if _, err := GetAppNameFromHooks(filepath.Join(t.TempDir(), "hooks.py")); err == nil {
+		t.Errorf("Expected error for missing hooks.py, got nil")
+	}
+}
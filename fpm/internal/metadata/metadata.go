@@ -2,10 +2,44 @@ package metadata
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
+// DefaultFileName is the standard name of the metadata file in app sources
+// and at the root of .fpm packages.
+const DefaultFileName = "app_metadata.json"
+
+// FileName is the name of the metadata file that is read and written. It
+// defaults to DefaultFileName and can be overridden, e.g. by fpm's
+// --metadata-file flag, for systems that expect another name.
+var FileName = DefaultFileName
+
+// reservedFileNames are the names fpm gives other parts of an app source
+// or package. Using one for the metadata would overwrite that part.
+var reservedFileNames = []string{
+	"app_source", "compiled_assets", "requirements.txt", "package.json",
+	"install_hooks.py", ".fpmignore", "_manifest.json", "_source.json",
+}
+
+// ValidateFileName checks that name can be used as FileName: a single
+// plain file name, so the metadata is always written at the root of the
+// directory it belongs to, that is not already part of the package layout.
+func ValidateFileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid metadata file name '%s': must be a plain file name without path separators", name)
+	}
+	for _, reserved := range reservedFileNames {
+		if name == reserved {
+			return fmt.Errorf("invalid metadata file name '%s': the name is already used by the package layout", name)
+		}
+	}
+	return nil
+}
+
 // AppMetadata defines the structure of the app_metadata.json file
 // that will be included in the .fpm package.
 type AppMetadata struct {
@@ -16,13 +50,33 @@ type AppMetadata struct {
 	Dependencies        map[string]string `json:"dependencies,omitempty"` // e.g., "erpnext": "13.2.1"
 	FrappeCompatibility []string          `json:"frappeCompatibility,omitempty"` // e.g., ["13.x.x", "14.x.x"]
 	Hooks               map[string]string `json:"hooks,omitempty"` // e.g., "install_hooks": "install_hooks.py"
+	Labels              map[string]string `json:"labels,omitempty"` // e.g., "build": "1234", "ci_job": "https://ci/job/1"
+	ContentChecksum     string            `json:"contentChecksum,omitempty"` // SHA256 over the packaged files, excluding app_metadata.json
+	Conflicts           []string          `json:"conflicts,omitempty"` // e.g., ["other_app", "someorg/*"]
 	// Add other fields as necessary from the vision document's package structure
 }
 
-// LoadAppMetadata loads metadata from app_metadata.json file in the given appPath.
+// ConflictsWith reports whether the app declares a conflict with appName
+// and returns the matching Conflicts pattern. Patterns may be prefixed with
+// an organisation ("org/app"); only the app part is matched, using
+// path.Match glob syntax.
+func (m *AppMetadata) ConflictsWith(appName string) (string, bool) {
+	for _, pattern := range m.Conflicts {
+		appPattern := pattern
+		if i := strings.LastIndex(appPattern, "/"); i >= 0 {
+			appPattern = appPattern[i+1:]
+		}
+		if matched, err := path.Match(appPattern, appName); err == nil && matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// LoadAppMetadata loads metadata from the metadata file (see FileName) in the given appPath.
 // If the file doesn't exist, it returns an empty AppMetadata struct and no error.
 func LoadAppMetadata(appPath string) (*AppMetadata, error) {
-	metadataFilePath := filepath.Join(appPath, "app_metadata.json")
+	metadataFilePath := filepath.Join(appPath, FileName)
 	data := &AppMetadata{
 		Dependencies:        make(map[string]string),
 		FrappeCompatibility: make([]string, 0),
@@ -48,13 +102,35 @@ func LoadAppMetadata(appPath string) (*AppMetadata, error) {
 }
 
 // GenerateAppMetadata creates a basic AppMetadata struct.
-// It infers the packageName from the appPath's directory name.
-// It sets the packageVersion from the provided argument.
+// It infers the packageName from a setuptools setup.cfg/setup.py in appPath,
+// falling back to the appPath's directory name.
+// It sets the packageVersion from the provided argument, or from the
+// setuptools files when the argument is empty.
 func GenerateAppMetadata(appPath string, version string) (*AppMetadata, error) {
 	absPath, err := filepath.Abs(appPath)
 	if err != nil {
 		return nil, err
 	}
+
+	setupName, setupVersion, err := ReadSetupMetadata(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		version = setupVersion
+	}
+	if setupName != "" {
+		// Distribution names may use hyphens, but the Frappe module cannot
+		packageName := strings.ReplaceAll(setupName, "-", "_")
+		return &AppMetadata{
+			PackageName:         packageName,
+			PackageVersion:      version,
+			Dependencies:        make(map[string]string),
+			FrappeCompatibility: make([]string, 0),
+			Hooks:               make(map[string]string),
+		}, nil
+	}
+
 	// Infer package name from the directory name
 	// This might need to be more sophisticated, e.g. looking for a specific module name
 	packageName := filepath.Base(absPath)
@@ -86,10 +162,10 @@ func GenerateAppMetadata(appPath string, version string) (*AppMetadata, error) {
 	}, nil
 }
 
-// SaveAppMetadata saves the AppMetadata struct to an app_metadata.json file
+// SaveAppMetadata saves the AppMetadata struct to a metadata file (see FileName)
 // in the specified directory (usually the staging directory for the package).
 func SaveAppMetadata(targetDir string, data *AppMetadata) error {
-	metadataFilePath := filepath.Join(targetDir, "app_metadata.json")
+	metadataFilePath := filepath.Join(targetDir, FileName)
 	fileBytes, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return err
@@ -127,6 +127,7 @@ func TestSaveAndLoadAppMetadata(t *testing.T) {
 		Dependencies:   map[string]string{"frappe": "14.0.0"},
         FrappeCompatibility: []string{"14.x.x"},
         Hooks: map[string]string{"install": "install.py"},
+		Labels:         map[string]string{"build": "42"},
 	}
 
 	err = SaveAppMetadata(tmpDir, metaToSave)
@@ -143,3 +144,146 @@ func TestSaveAndLoadAppMetadata(t *testing.T) {
 		t.Errorf("Loaded metadata after save does not match original. Got %+v, want %+v", loadedMeta, metaToSave)
 	}
 }
+
+func TestReadSetupMetadata(t *testing.T) {
+	testCases := []struct {
+		name            string
+		files           map[string]string
+		expectedName    string
+		expectedVersion string
+	}{
+		{
+			name: "setup.cfg metadata section",
+			files: map[string]string{"setup.cfg": `[options]
+name = not_this_one
+
+[metadata]
+name =   cfg_app  
+version = "1.4.0"
+description = A setuptools app
+`},
+			expectedName:    "cfg_app",
+			expectedVersion: "1.4.0",
+		},
+		{
+			name: "setup.cfg attr directive is ignored",
+			files: map[string]string{"setup.cfg": `[metadata]
+name: 'quoted_app'
+version = attr: quoted_app.__version__
+`},
+			expectedName:    "quoted_app",
+			expectedVersion: "",
+		},
+		{
+			name: "setup.py string literals",
+			files: map[string]string{"setup.py": `from setuptools import setup, find_packages
+from py_app import __version__ as version
+
+setup(
+	name = 'py_app',
+	version=version,
+	packages=find_packages(),
+)
+`},
+			expectedName:    "py_app",
+			expectedVersion: "",
+		},
+		{
+			name: "setup.py fills in version missing from setup.cfg",
+			files: map[string]string{
+				"setup.cfg": "[metadata]\nname = mixed_app\n",
+				"setup.py":  `setup(name="ignored_name", version="2.0.1")`,
+			},
+			expectedName:    "mixed_app",
+			expectedVersion: "2.0.1",
+		},
+		{
+			name:            "no setuptools files",
+			files:           map[string]string{},
+			expectedName:    "",
+			expectedVersion: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for fName, content := range tc.files {
+				if err := os.WriteFile(filepath.Join(tmpDir, fName), []byte(content), 0644); err != nil {
+					t.Fatalf("Failed to write %s: %v", fName, err)
+				}
+			}
+
+			name, version, err := ReadSetupMetadata(tmpDir)
+			if err != nil {
+				t.Fatalf("ReadSetupMetadata failed: %v", err)
+			}
+			if name != tc.expectedName {
+				t.Errorf("Name mismatch. Got '%s', want '%s'", name, tc.expectedName)
+			}
+			if version != tc.expectedVersion {
+				t.Errorf("Version mismatch. Got '%s', want '%s'", version, tc.expectedVersion)
+			}
+		})
+	}
+}
+
+func TestGenerateAppMetadataFromSetupCfg(t *testing.T) {
+	tmpAppDir := t.TempDir()
+	setupCfg := "[metadata]\nname = frappe-setup-app\nversion = 0.9.0\n"
+	if err := os.WriteFile(filepath.Join(tmpAppDir, "setup.cfg"), []byte(setupCfg), 0644); err != nil {
+		t.Fatalf("Failed to write setup.cfg: %v", err)
+	}
+
+	generatedMeta, err := GenerateAppMetadata(tmpAppDir, "")
+	if err != nil {
+		t.Fatalf("GenerateAppMetadata failed: %v", err)
+	}
+	if generatedMeta.PackageName != "frappe_setup_app" {
+		t.Errorf("Generated package name mismatch. Got %s, want %s", generatedMeta.PackageName, "frappe_setup_app")
+	}
+	if generatedMeta.PackageVersion != "0.9.0" {
+		t.Errorf("Generated package version mismatch. Got %s, want %s", generatedMeta.PackageVersion, "0.9.0")
+	}
+
+	// An explicit version still takes precedence
+	generatedMeta, err = GenerateAppMetadata(tmpAppDir, "1.0.0")
+	if err != nil {
+		t.Fatalf("GenerateAppMetadata failed: %v", err)
+	}
+	if generatedMeta.PackageVersion != "1.0.0" {
+		t.Errorf("Expected explicit version to win. Got %s", generatedMeta.PackageVersion)
+	}
+}
+
+func TestConflictsWith(t *testing.T) {
+	meta := &AppMetadata{Conflicts: []string{"myorg/legacy_hr", "payroll_*"}}
+	testCases := []struct {
+		appName string
+		pattern string
+		want    bool
+	}{
+		{"legacy_hr", "myorg/legacy_hr", true},
+		{"payroll_india", "payroll_*", true},
+		{"hrms", "", false},
+	}
+	for _, tc := range testCases {
+		pattern, conflicts := meta.ConflictsWith(tc.appName)
+		if conflicts != tc.want || pattern != tc.pattern {
+			t.Errorf("ConflictsWith(%q) = (%q, %v), want (%q, %v)", tc.appName, pattern, conflicts, tc.pattern, tc.want)
+		}
+	}
+}
+
+func TestValidateFileName(t *testing.T) {
+	for _, name := range []string{"app_metadata.json", "fpm.json", ".meta"} {
+		if err := ValidateFileName(name); err != nil {
+			t.Errorf("Expected '%s' to be accepted, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".", "..", "../x.json", "sub/meta.json", `sub\meta.json`, "/abs.json", "requirements.txt", "app_source", "_manifest.json"} {
+		if err := ValidateFileName(name); err == nil {
+			t.Errorf("Expected '%s' to be rejected", name)
+		}
+	}
+}
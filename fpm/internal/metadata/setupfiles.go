@@ -0,0 +1,133 @@
+package metadata
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// setupCallRegex finds the start of a setup(...) call in setup.py.
+	setupCallRegex = regexp.MustCompile(`\bsetup\s*\(`)
+	// setupKeywordRegex matches a string-literal keyword argument such as name="my_app".
+	setupKeywordRegex = regexp.MustCompile(`\b(name|version)\s*=\s*(?:'([^'\n]*)'|"([^"\n]*)")`)
+)
+
+// ReadSetupMetadata reads the package name and version declared by a
+// setuptools-based app in appPath. setup.cfg's [metadata] section is
+// consulted first, then string literals passed to setup() in setup.py.
+// Values that are not plain literals (e.g. version=__version__ or
+// "attr:"/"file:" directives) are ignored. Empty strings are returned
+// when nothing could be determined or neither file exists.
+func ReadSetupMetadata(appPath string) (name string, version string, err error) {
+	name, version, err = readSetupCfg(filepath.Join(appPath, "setup.cfg"))
+	if err != nil {
+		return "", "", err
+	}
+	if name != "" && version != "" {
+		return name, version, nil
+	}
+
+	pyName, pyVersion, err := readSetupPy(filepath.Join(appPath, "setup.py"))
+	if err != nil {
+		return "", "", err
+	}
+	if name == "" {
+		name = pyName
+	}
+	if version == "" {
+		version = pyVersion
+	}
+	return name, version, nil
+}
+
+// readSetupCfg extracts name and version from the [metadata] section of a setup.cfg.
+func readSetupCfg(path string) (name string, version string, err error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	inMetadata := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inMetadata = strings.TrimSpace(line[1:len(line)-1]) == "metadata"
+			continue
+		}
+		if !inMetadata {
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:sep])
+		value := unquote(strings.TrimSpace(line[sep+1:]))
+		if strings.HasPrefix(value, "attr:") || strings.HasPrefix(value, "file:") {
+			continue // Dynamic values cannot be resolved without running Python
+		}
+		switch key {
+		case "name":
+			name = value
+		case "version":
+			version = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+	return name, version, nil
+}
+
+// readSetupPy extracts string-literal name and version arguments of the setup() call in a setup.py.
+func readSetupPy(path string) (name string, version string, err error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	loc := setupCallRegex.FindIndex(content)
+	if loc == nil {
+		return "", "", nil
+	}
+	for _, match := range setupKeywordRegex.FindAllSubmatch(content[loc[1]:], -1) {
+		value := strings.TrimSpace(string(match[2]) + string(match[3]))
+		switch string(match[1]) {
+		case "name":
+			if name == "" {
+				name = value
+			}
+		case "version":
+			if version == "" {
+				version = value
+			}
+		}
+	}
+	return name, version, nil
+}
+
+// unquote strips a single layer of matching quotes and surrounding whitespace.
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return strings.TrimSpace(value[1 : len(value)-1])
+		}
+	}
+	return value
+}
@@ -0,0 +1,121 @@
+package sbom
+
+import (
+	"sort"
+	"time"
+
+	"fpm/internal/metadata"
+)
+
+// CycloneDX document format identifiers.
+const (
+	BOMFormat   = "CycloneDX"
+	SpecVersion = "1.5"
+)
+
+// Document is a CycloneDX JSON document, limited to the fields FPM fills in.
+type Document struct {
+	BOMFormat    string       `json:"bomFormat"`
+	SpecVersion  string       `json:"specVersion"`
+	Version      int          `json:"version"`
+	Metadata     Metadata     `json:"metadata"`
+	Components   []Component  `json:"components,omitempty"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// Metadata describes the document and the component it was generated for.
+type Metadata struct {
+	Timestamp string    `json:"timestamp"`
+	Tools     []Tool    `json:"tools,omitempty"`
+	Component Component `json:"component"`
+}
+
+// Tool identifies the software that produced the document.
+type Tool struct {
+	Name string `json:"name"`
+}
+
+// Component is a CycloneDX component.
+type Component struct {
+	BOMRef     string     `json:"bom-ref"`
+	Type       string     `json:"type"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	Hashes     []Hash     `json:"hashes,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// Hash is a CycloneDX hash of a component.
+type Hash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// Property is a CycloneDX name/value property.
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Dependency lists the components a component depends on, by bom-ref.
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// FromMetadata builds a CycloneDX document for the app described by meta.
+// packageSHA256, if not empty, is the SHA-256 of the .fpm file itself.
+// Declared dependencies become components whose version is the declared
+// version requirement, since the installed versions are not known here.
+func FromMetadata(meta *metadata.AppMetadata, packageSHA256 string, now time.Time) *Document {
+	app := Component{
+		BOMRef:  "pkg:fpm/" + meta.PackageName + "@" + meta.PackageVersion,
+		Type:    "application",
+		Name:    meta.PackageName,
+		Version: meta.PackageVersion,
+	}
+	if packageSHA256 != "" {
+		app.Hashes = []Hash{{Alg: "SHA-256", Content: packageSHA256}}
+	}
+	if meta.ContentChecksum != "" {
+		app.Properties = append(app.Properties, Property{Name: "fpm:contentChecksum", Value: meta.ContentChecksum})
+	}
+	for _, key := range sortedKeys(meta.Labels) {
+		app.Properties = append(app.Properties, Property{Name: "fpm:label:" + key, Value: meta.Labels[key]})
+	}
+
+	doc := &Document{
+		BOMFormat:   BOMFormat,
+		SpecVersion: SpecVersion,
+		Version:     1,
+		Metadata: Metadata{
+			Timestamp: now.UTC().Format(time.RFC3339),
+			Tools:     []Tool{{Name: "fpm"}},
+			Component: app,
+		},
+	}
+
+	appDependency := Dependency{Ref: app.BOMRef}
+	for _, name := range sortedKeys(meta.Dependencies) {
+		dep := Component{
+			BOMRef:  "pkg:fpm/" + name,
+			Type:    "application",
+			Name:    name,
+			Version: meta.Dependencies[name],
+		}
+		doc.Components = append(doc.Components, dep)
+		appDependency.DependsOn = append(appDependency.DependsOn, dep.BOMRef)
+	}
+	doc.Dependencies = []Dependency{appDependency}
+	return doc
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
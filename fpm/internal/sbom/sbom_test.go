@@ -0,0 +1,44 @@
+package sbom
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"fpm/internal/metadata"
+)
+
+func TestFromMetadata(t *testing.T) {
+	meta := &metadata.AppMetadata{
+		PackageName:     "my_app",
+		PackageVersion:  "1.2.0",
+		ContentChecksum: "abc123",
+		Dependencies:    map[string]string{"frappe": ">=15.0.0", "erpnext": "15.1.0"},
+		Labels:          map[string]string{"build": "42"},
+	}
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	doc := FromMetadata(meta, "deadbeef", now)
+
+	if doc.BOMFormat != "CycloneDX" || doc.SpecVersion != "1.5" || doc.Metadata.Timestamp != "2025-01-02T03:04:05Z" {
+		t.Errorf("Unexpected document header: %+v", doc)
+	}
+	app := doc.Metadata.Component
+	if app.BOMRef != "pkg:fpm/my_app@1.2.0" || app.Name != "my_app" || app.Version != "1.2.0" {
+		t.Errorf("Unexpected app component: %+v", app)
+	}
+	if !reflect.DeepEqual(app.Hashes, []Hash{{Alg: "SHA-256", Content: "deadbeef"}}) {
+		t.Errorf("Unexpected hashes: %+v", app.Hashes)
+	}
+	expectedProps := []Property{{"fpm:contentChecksum", "abc123"}, {"fpm:label:build", "42"}}
+	if !reflect.DeepEqual(app.Properties, expectedProps) {
+		t.Errorf("Unexpected properties: %+v", app.Properties)
+	}
+
+	if len(doc.Components) != 2 || doc.Components[0].Name != "erpnext" || doc.Components[1].Version != ">=15.0.0" {
+		t.Errorf("Unexpected dependency components: %+v", doc.Components)
+	}
+	expectedDeps := []Dependency{{Ref: "pkg:fpm/my_app@1.2.0", DependsOn: []string{"pkg:fpm/erpnext", "pkg:fpm/frappe"}}}
+	if !reflect.DeepEqual(doc.Dependencies, expectedDeps) {
+		t.Errorf("Unexpected dependencies: %+v", doc.Dependencies)
+	}
+}
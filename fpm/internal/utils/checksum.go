@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CalculateFileChecksum returns the hex-encoded SHA256 digest of the file at path.
+func CalculateFileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// CalculateDirectoryChecksum returns a hex-encoded SHA256 digest over the
+// contents of dir. Each file contributes its slash-separated path relative
+// to dir and the SHA256 of its content (the link target for symlinks), in
+// sorted path order, so the result does not depend on walk order,
+// timestamps or permissions. Paths relative to dir listed in ignore are
+// left out, which lets callers exclude files such as app_metadata.json
+// that embed the checksum itself.
+func CalculateDirectoryChecksum(dir string, ignore ...string) (string, error) {
+	return CalculateDirectoryChecksumCached(dir, nil, ignore...)
+}
+
+// CalculateDirectoryChecksumCached is like CalculateDirectoryChecksum but
+// reuses the per-file hashes in cache for files whose relative path, size
+// and modification time are unchanged, and records the hashes it computes.
+// A nil cache hashes every file. The result is the same either way.
+func CalculateDirectoryChecksumCached(dir string, cache *ChecksumCache, ignore ...string) (string, error) {
+	ignored := make(map[string]bool, len(ignore))
+	for _, p := range ignore {
+		ignored[filepath.ToSlash(p)] = true
+	}
+
+	entries := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if ignored[relPath] {
+			return nil
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256([]byte("symlink:" + filepath.ToSlash(target)))
+			entries[relPath] = hex.EncodeToString(sum[:])
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if sum, ok := cache.lookup(relPath, info); ok {
+			entries[relPath] = sum
+			return nil
+		}
+		sum, err := CalculateFileChecksum(path)
+		if err != nil {
+			return err
+		}
+		cache.store(relPath, info, sum)
+		entries[relPath] = sum
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum directory %s: %w", dir, err)
+	}
+
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	cache.prune(entries)
+
+	hasher := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(hasher, "%s\x00%s\n", p, entries[p])
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// ChecksumCache stores per-file SHA256 hashes keyed by relative path, size
+// and modification time, so unchanged files need not be re-read.
+type ChecksumCache struct {
+	path    string
+	Entries map[string]checksumCacheEntry `json:"entries"`
+}
+
+// checksumCacheEntry is the cached hash of one file.
+type checksumCacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"` // Unix nanoseconds
+	SHA256  string `json:"sha256"`
+}
+
+// LoadChecksumCache reads the checksum cache at path. A missing or
+// unreadable cache yields an empty one, since the cache only saves work.
+func LoadChecksumCache(path string) *ChecksumCache {
+	cache := &ChecksumCache{path: path, Entries: make(map[string]checksumCacheEntry)}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(content, cache); err != nil || cache.Entries == nil {
+		cache.Entries = make(map[string]checksumCacheEntry)
+	}
+	return cache
+}
+
+// Save writes the cache back to the path it was loaded from.
+func (c *ChecksumCache) Save() error {
+	content, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, content, 0644)
+}
+
+// lookup returns the cached hash of relPath if its size and modification
+// time still match info.
+func (c *ChecksumCache) lookup(relPath string, info fs.FileInfo) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	entry, ok := c.Entries[relPath]
+	if !ok || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		return "", false
+	}
+	return entry.SHA256, true
+}
+
+// prune drops cached entries for files that are no longer present.
+func (c *ChecksumCache) prune(present map[string]string) {
+	if c == nil {
+		return
+	}
+	for relPath := range c.Entries {
+		if _, ok := present[relPath]; !ok {
+			delete(c.Entries, relPath)
+		}
+	}
+}
+
+// store records the hash of relPath for the file described by info.
+func (c *ChecksumCache) store(relPath string, info fs.FileInfo, sum string) {
+	if c == nil {
+		return
+	}
+	c.Entries[relPath] = checksumCacheEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), SHA256: sum}
+}
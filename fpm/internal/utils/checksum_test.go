@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCalculateDirectoryChecksum(t *testing.T) {
+	writeFile := func(dir, rel, content string) {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	dirA := t.TempDir()
+	writeFile(dirA, "a.txt", "alpha")
+	writeFile(dirA, "sub/b.txt", "beta")
+
+	dirB := t.TempDir()
+	writeFile(dirB, "sub/b.txt", "beta")
+	writeFile(dirB, "a.txt", "alpha")
+	writeFile(dirB, "app_metadata.json", "{}")
+
+	sumA, err := CalculateDirectoryChecksum(dirA)
+	if err != nil {
+		t.Fatalf("CalculateDirectoryChecksum failed: %v", err)
+	}
+	sumB, err := CalculateDirectoryChecksum(dirB, "app_metadata.json")
+	if err != nil {
+		t.Fatalf("CalculateDirectoryChecksum failed: %v", err)
+	}
+	if sumA != sumB {
+		t.Errorf("Expected equal checksums for equal content, got %s and %s", sumA, sumB)
+	}
+
+	writeFile(dirB, "sub/b.txt", "changed")
+	sumB, err = CalculateDirectoryChecksum(dirB, "app_metadata.json")
+	if err != nil {
+		t.Fatalf("CalculateDirectoryChecksum failed: %v", err)
+	}
+	if sumA == sumB {
+		t.Errorf("Expected checksum to change when file content changes")
+	}
+}
+
+func TestCalculateDirectoryChecksumCached(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(filePath, []byte("alpha"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	cachePath := filepath.Join(t.TempDir(), "cache", "checksums.json")
+
+	uncached, err := CalculateDirectoryChecksum(dir)
+	if err != nil {
+		t.Fatalf("CalculateDirectoryChecksum failed: %v", err)
+	}
+	cache := LoadChecksumCache(cachePath)
+	cached, err := CalculateDirectoryChecksumCached(dir, cache)
+	if err != nil {
+		t.Fatalf("CalculateDirectoryChecksumCached failed: %v", err)
+	}
+	if cached != uncached {
+		t.Errorf("Cached checksum %s differs from uncached %s", cached, uncached)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+
+	// An entry matching size and mtime is trusted without re-reading the file
+	cache = LoadChecksumCache(cachePath)
+	entry := cache.Entries["a.txt"]
+	entry.SHA256 = "0000"
+	cache.Entries["a.txt"] = entry
+	if sum, _ := CalculateDirectoryChecksumCached(dir, cache); sum == uncached {
+		t.Errorf("Expected the cached per-file hash to be used for an unchanged file")
+	}
+
+	// Changing the file invalidates its entry
+	if err := os.WriteFile(filePath, []byte("gamma"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite file: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filePath, later, later); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+	changedUncached, err := CalculateDirectoryChecksum(dir)
+	if err != nil {
+		t.Fatalf("CalculateDirectoryChecksum failed: %v", err)
+	}
+	changedCached, err := CalculateDirectoryChecksumCached(dir, cache)
+	if err != nil {
+		t.Fatalf("CalculateDirectoryChecksumCached failed: %v", err)
+	}
+	if changedCached != changedUncached || changedCached == uncached {
+		t.Errorf("Expected the modified file to be re-hashed, got %s want %s", changedCached, changedUncached)
+	}
+	if cache.Entries["a.txt"].SHA256 == "0000" {
+		t.Errorf("Expected the stale cache entry to be replaced")
+	}
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"sync"
+)
+
+// cleanupRegistry tracks cleanup functions for work that is in progress,
+// such as staging directories and partially written files, so they can be
+// removed if the process is interrupted before normal cleanup runs.
+var cleanupRegistry = struct {
+	sync.Mutex
+	nextID int
+	fns    map[int]func()
+	order  []int
+}{fns: make(map[int]func())}
+
+// RegisterCleanup registers fn to be run by RunCleanups. The returned
+// function unregisters fn and should be called once the work it guards
+// has finished (successfully or not) and been cleaned up normally.
+func RegisterCleanup(fn func()) (unregister func()) {
+	cleanupRegistry.Lock()
+	defer cleanupRegistry.Unlock()
+
+	id := cleanupRegistry.nextID
+	cleanupRegistry.nextID++
+	cleanupRegistry.fns[id] = fn
+	cleanupRegistry.order = append(cleanupRegistry.order, id)
+
+	return func() {
+		cleanupRegistry.Lock()
+		defer cleanupRegistry.Unlock()
+		delete(cleanupRegistry.fns, id)
+		for i, registered := range cleanupRegistry.order {
+			if registered == id {
+				cleanupRegistry.order = append(cleanupRegistry.order[:i], cleanupRegistry.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// RunCleanups runs every registered cleanup function, most recently
+// registered first, and clears the registry.
+func RunCleanups() {
+	cleanupRegistry.Lock()
+	var fns []func()
+	for i := len(cleanupRegistry.order) - 1; i >= 0; i-- {
+		if fn, ok := cleanupRegistry.fns[cleanupRegistry.order[i]]; ok {
+			fns = append(fns, fn)
+		}
+	}
+	cleanupRegistry.fns = make(map[int]func())
+	cleanupRegistry.order = nil
+	cleanupRegistry.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
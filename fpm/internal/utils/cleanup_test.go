@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRunCleanups(t *testing.T) {
+	var calls []string
+
+	RegisterCleanup(func() { calls = append(calls, "first") })
+	unregister := RegisterCleanup(func() { calls = append(calls, "unregistered") })
+	RegisterCleanup(func() { calls = append(calls, "last") })
+	unregister()
+
+	RunCleanups()
+
+	expected := []string{"last", "first"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Cleanup call order mismatch. Got %v, want %v", calls, expected)
+	}
+
+	// The registry is cleared after running
+	calls = nil
+	RunCleanups()
+	if len(calls) != 0 {
+		t.Errorf("Expected no cleanups after registry was cleared, got %v", calls)
+	}
+}
+
+func TestRunCleanupsRemovesPartialFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Simulate an interrupted operation that left a staging dir and a partial file behind
+	stagingDir := filepath.Join(tmpDir, "fpm-staging-myapp-123")
+	if err := os.MkdirAll(filepath.Join(stagingDir, "app_source"), 0755); err != nil {
+		t.Fatalf("Failed to create staging dir: %v", err)
+	}
+	partialFile := filepath.Join(tmpDir, "myapp-1.0.0.fpm")
+	if err := os.WriteFile(partialFile, []byte("partial"), 0644); err != nil {
+		t.Fatalf("Failed to write partial file: %v", err)
+	}
+
+	RegisterCleanup(func() { os.RemoveAll(stagingDir) })
+	RegisterCleanup(func() { os.Remove(partialFile) })
+
+	RunCleanups()
+
+	if _, err := os.Stat(stagingDir); !os.IsNotExist(err) {
+		t.Errorf("Expected staging dir to be removed, got err %v", err)
+	}
+	if _, err := os.Stat(partialFile); !os.IsNotExist(err) {
+		t.Errorf("Expected partial file to be removed, got err %v", err)
+	}
+}
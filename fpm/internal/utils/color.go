@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"io"
+	"os"
+)
+
+// ANSI escape sequences used to highlight output.
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// NoColor disables colored output regardless of the output destination.
+// It is set from the --no-color flag.
+var NoColor bool
+
+// ColorEnabled reports whether output written to w should be colored:
+// w must be a terminal, and neither NoColor nor the NO_COLOR environment
+// variable (https://no-color.org) may be set.
+func ColorEnabled(w io.Writer) bool {
+	if NoColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w is a terminal. It is a variable so tests
+// can simulate one.
+var isTerminal = func(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Success, Warning and Error return s highlighted in green, yellow and red
+// respectively when ColorEnabled(w), and s unchanged otherwise.
+func Success(w io.Writer, s string) string { return colorize(w, colorGreen, s) }
+
+func Warning(w io.Writer, s string) string { return colorize(w, colorYellow, s) }
+
+func Error(w io.Writer, s string) string { return colorize(w, colorRed, s) }
+
+// colorize wraps s in the given color when output to w should be colored.
+func colorize(w io.Writer, color string, s string) string {
+	if !ColorEnabled(w) {
+		return s
+	}
+	return color + s + colorReset
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestColorDisabledForNonTerminals(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	var buf bytes.Buffer
+	if got := Success(&buf, "done"); got != "done" {
+		t.Errorf("Expected no ANSI codes for a buffer, got %q", got)
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer file.Close()
+	if got := Warning(file, "careful"); got != "careful" {
+		t.Errorf("Expected no ANSI codes for a regular file, got %q", got)
+	}
+}
+
+func TestColorDisabledByFlagAndEnv(t *testing.T) {
+	defer func(orig func(io.Writer) bool) { isTerminal = orig }(isTerminal)
+	isTerminal = func(io.Writer) bool { return true }
+	var buf bytes.Buffer
+
+	t.Setenv("NO_COLOR", "")
+	if got := Error(&buf, "failed"); got != colorRed+"failed"+colorReset {
+		t.Errorf("Expected red output for a terminal, got %q", got)
+	}
+
+	NoColor = true
+	got := Error(&buf, "failed")
+	NoColor = false
+	if got != "failed" {
+		t.Errorf("Expected NoColor to disable colors, got %q", got)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if got := Error(&buf, "failed"); got != "failed" {
+		t.Errorf("Expected NO_COLOR to disable colors, got %q", got)
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrNotGitRepository is returned by GitWorkTreeDirty when dir is not inside a git work tree.
+var ErrNotGitRepository = errors.New("not a git repository")
+
+// GitWorkTreeDirty reports whether dir, in the git work tree containing it,
+// has uncommitted changes, including untracked files that are not ignored.
+// Changes elsewhere in the work tree are not considered, so an app in a
+// subdirectory of a larger repository is judged by its own files only.
+// It returns ErrNotGitRepository if dir is not inside a git work tree.
+func GitWorkTreeDirty(dir string) (bool, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return false, fmt.Errorf("git executable not found: %w", err)
+	}
+
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "true" {
+		return false, ErrNotGitRepository
+	}
+
+	out, err = exec.Command("git", "-C", dir, "status", "--porcelain", "--", ".").Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to get git status for %s: %w", dir, err)
+	}
+	return strings.TrimSpace(string(out)) != "", nil
+}
@@ -0,0 +1,60 @@
+package version
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Validate checks that v is a strict semantic version (MAJOR.MINOR.PATCH
+// with optional pre-release and build metadata, no "v" prefix). The
+// returned error suggests a corrected version when one can be derived.
+func Validate(v string) error {
+	if _, err := semver.StrictNewVersion(v); err == nil {
+		return nil
+	}
+	if lenient, err := semver.NewVersion(v); err == nil {
+		return fmt.Errorf("version '%s' is not a valid semantic version; use '%s' instead (format MAJOR.MINOR.PATCH)", v, lenient.String())
+	}
+	return fmt.Errorf("version '%s' is not a valid semantic version; expected MAJOR.MINOR.PATCH, e.g. 1.0.0 or 1.0.0-rc1", v)
+}
+
+// Sort returns the names that parse as semantic versions, such as version
+// directory names, ordered from lowest to highest by semantic version
+// precedence, so 1.9.0 sorts before 1.10.0 and 1.0.0-rc1 before 1.0.0.
+// Names that are not versions are returned in skipped, in their original
+// order, for the caller to warn about.
+func Sort(names []string) (sorted []string, skipped []string) {
+	type parsed struct {
+		name    string
+		version *semver.Version
+	}
+	var versions []parsed
+	for _, name := range names {
+		v, err := semver.NewVersion(name)
+		if err != nil {
+			skipped = append(skipped, name)
+			continue
+		}
+		versions = append(versions, parsed{name, v})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].version.LessThan(versions[j].version)
+	})
+	for _, v := range versions {
+		sorted = append(sorted, v.name)
+	}
+	return sorted, skipped
+}
+
+// Latest returns the highest semantic version among names, or "" if none
+// of them is a version. Names that are not versions are returned in
+// skipped, as for Sort.
+func Latest(names []string) (latest string, skipped []string) {
+	sorted, skipped := Sort(names)
+	if len(sorted) == 0 {
+		return "", skipped
+	}
+	return sorted[len(sorted)-1], skipped
+}
@@ -0,0 +1,65 @@
+package version
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []string{"1.0.0", "0.1.2", "1.10.0", "2.0.0-rc1", "1.0.0+build.5"}
+	for _, v := range valid {
+		if err := Validate(v); err != nil {
+			t.Errorf("Expected '%s' to be valid, got %v", v, err)
+		}
+	}
+
+	invalid := []struct {
+		version    string
+		suggestion string
+	}{
+		{"1.0", "use '1.0.0' instead"},
+		{"v1.2.3", "use '1.2.3' instead"},
+		{"1.0.x", "expected MAJOR.MINOR.PATCH"},
+		{"latest", "expected MAJOR.MINOR.PATCH"},
+	}
+	for _, tc := range invalid {
+		err := Validate(tc.version)
+		if err == nil {
+			t.Errorf("Expected '%s' to be invalid, got nil", tc.version)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.suggestion) {
+			t.Errorf("Expected error for '%s' to contain '%s', got '%s'", tc.version, tc.suggestion, err.Error())
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	sorted, skipped := Sort([]string{"1.10.0", "1.0.0", "not-a-version", "1.9.0", "1.0.0-rc1", "1.0.0-beta"})
+	wantSorted := []string{"1.0.0-beta", "1.0.0-rc1", "1.0.0", "1.9.0", "1.10.0"}
+	if !reflect.DeepEqual(sorted, wantSorted) {
+		t.Errorf("Sort() = %v, want %v", sorted, wantSorted)
+	}
+	if !reflect.DeepEqual(skipped, []string{"not-a-version"}) {
+		t.Errorf("Expected non-semver names to be skipped, got %v", skipped)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	testCases := []struct {
+		names  []string
+		latest string
+	}{
+		{[]string{"1.9.0", "1.10.0"}, "1.10.0"},
+		{[]string{"1.0.0", "1.0.0-rc1"}, "1.0.0"},
+		{[]string{"2.0.0-rc1", "1.10.0"}, "2.0.0-rc1"},
+		{[]string{"latest", "tmp"}, ""},
+		{nil, ""},
+	}
+	for _, tc := range testCases {
+		if latest, _ := Latest(tc.names); latest != tc.latest {
+			t.Errorf("Latest(%v) = '%s', want '%s'", tc.names, latest, tc.latest)
+		}
+	}
+}